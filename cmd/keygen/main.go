@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// keygen writes a new JWT signing key into the keys directory. With the
+// default kid (a timestamp) the new key becomes the active signing key on the
+// next restart, while older keys keep verifying tokens they signed. To retire
+// an old key, replace its <kid>.pem with the <kid>.pub.pem written alongside
+// it, and delete that once its tokens have expired.
+func main() {
+	dir := flag.String("dir", "storages/keys", "directory holding JWT keys")
+	alg := flag.String("alg", "ed25519", "key algorithm: ed25519 or rs256")
+	kid := flag.String("kid", time.Now().UTC().Format("20060102150405"), "key ID")
+	bits := flag.Int("bits", 2048, "RSA key size")
+	flag.Parse()
+
+	var private, public interface{}
+	switch *alg {
+	case "ed25519":
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			log.Fatal("Failed to generate key:", err)
+		}
+		private, public = priv, pub
+	case "rs256":
+		priv, err := rsa.GenerateKey(rand.Reader, *bits)
+		if err != nil {
+			log.Fatal("Failed to generate key:", err)
+		}
+		private, public = priv, &priv.PublicKey
+	default:
+		fmt.Println("Expected -alg ed25519 or rs256")
+		os.Exit(1)
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		log.Fatal("Failed to encode private key:", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		log.Fatal("Failed to encode public key:", err)
+	}
+
+	if err := os.MkdirAll(*dir, 0700); err != nil {
+		log.Fatal("Failed to create key directory:", err)
+	}
+
+	privPath := filepath.Join(*dir, *kid+".pem")
+	pubPath := filepath.Join(*dir, *kid+".pub.pem")
+	if err := writePEM(privPath, "PRIVATE KEY", privDER, 0600); err != nil {
+		log.Fatal("Failed to write private key:", err)
+	}
+	if err := writePEM(pubPath, "PUBLIC KEY", pubDER, 0644); err != nil {
+		log.Fatal("Failed to write public key:", err)
+	}
+
+	fmt.Printf("Generated %s key %q in %s\n", *alg, *kid, *dir)
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return pem.Encode(f, &pem.Block{Type: blockType, Bytes: der})
+}
@@ -2,6 +2,10 @@ package main
 
 import (
 	"bad_boyes/internal/config"
+	"bad_boyes/internal/fieldcrypt"
+	"bad_boyes/internal/phone"
+	"bad_boyes/internal/repository"
+	"bad_boyes/internal/services"
 	"flag"
 	"fmt"
 	"log"
@@ -19,10 +23,17 @@ func main() {
 	upCmd := flag.NewFlagSet("up", flag.ExitOnError)
 	downCmd := flag.NewFlagSet("down", flag.ExitOnError)
 	refreshCmd := flag.NewFlagSet("refresh", flag.ExitOnError)
+	backfillPhonesCmd := flag.NewFlagSet("backfill-phones", flag.ExitOnError)
+	region := backfillPhonesCmd.String("region", "", "default region for numbers without a country code (defaults to PHONE_DEFAULT_REGION)")
+	batchSize := backfillPhonesCmd.Int("batch", 500, "posts per batch")
+	dryRun := backfillPhonesCmd.Bool("dry-run", false, "report what would change without writing")
+	reencryptCmd := flag.NewFlagSet("reencrypt", flag.ExitOnError)
+	reencryptBatch := reencryptCmd.Int("batch", 500, "rows per batch")
+	reencryptDryRun := reencryptCmd.Bool("dry-run", false, "report what would change without writing")
 
 	// Check if command is provided
 	if len(os.Args) < 2 {
-		fmt.Println("Expected 'up', 'down', or 'refresh' command")
+		fmt.Println("Expected 'up', 'down', 'refresh', 'backfill-phones' or 'reencrypt' command")
 		os.Exit(1)
 	}
 
@@ -38,7 +49,9 @@ func main() {
 	dbPort := os.Getenv("DB_PORT")
 	dbName := os.Getenv("DB_NAME")
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+	// Migration files hold several statements each, which the driver only
+	// accepts with multiStatements
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local&multiStatements=true",
 		dbUser, dbPass, dbHost, dbPort, dbName)
 
 	db, err := gorm.Open(gormmysql.Open(dsn), &gorm.Config{})
@@ -91,8 +104,47 @@ func main() {
 		}
 		fmt.Println("Migrations refreshed successfully")
 
+	case "backfill-phones":
+		backfillPhonesCmd.Parse(os.Args[2:])
+		if *region == "" {
+			*region = os.Getenv("PHONE_DEFAULT_REGION")
+		}
+		phones, err := phone.NewNormalizer(*region)
+		if err != nil {
+			log.Fatal("Failed to initialize phone normalizer:", err)
+		}
+		useKeyRing()
+		backfill := services.NewPhoneBackfill(repository.NewPostRepository(db), repository.NewSubjectRepository(db), phones)
+		result, err := backfill.Run(*batchSize, *dryRun)
+		if err != nil {
+			log.Fatal("Failed to backfill phone numbers:", err)
+		}
+		fmt.Printf("Phone numbers backfilled: %d posts scanned, %d updated, %d invalid\n", result.Scanned, result.Updated, result.Invalid)
+
+	case "reencrypt":
+		reencryptCmd.Parse(os.Args[2:])
+		reencryption := services.NewFieldReencryption(repository.NewEncryptionRepository(db), repository.NewSubjectRepository(db), useKeyRing())
+		results, err := reencryption.Run(*reencryptBatch, *reencryptDryRun)
+		for _, result := range results {
+			fmt.Printf("%s: %d rows scanned, %d updated, %d merged\n", result.Table, result.Scanned, result.Updated, result.Merged)
+		}
+		if err != nil {
+			log.Fatal("Failed to re-encrypt rows:", err)
+		}
+
 	default:
-		fmt.Println("Expected 'up', 'down', or 'refresh' command")
+		fmt.Println("Expected 'up', 'down', 'refresh', 'backfill-phones' or 'reencrypt' command")
 		os.Exit(1)
 	}
 }
+
+// useKeyRing loads the field encryption key ring for commands that read or
+// write encrypted columns.
+func useKeyRing() *fieldcrypt.KeyRing {
+	keyRing, err := fieldcrypt.NewKeyRingFromEnv()
+	if err != nil {
+		log.Fatal("Failed to load field encryption keys:", err)
+	}
+	fieldcrypt.Use(keyRing)
+	return keyRing
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	"gorm.io/driver/mysql"
@@ -28,3 +30,45 @@ func InitDB() error {
 	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
 	return err
 }
+
+// GetDuration reads a duration such as "15m" or "720h" from the environment,
+// falling back to the given default when the variable is unset or invalid.
+func GetDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback
+	}
+	return d
+}
+
+// GetBool reads a boolean such as "true" or "0" from the environment, falling
+// back to the given default when the variable is unset or invalid.
+func GetBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fallback
+	}
+	return b
+}
+
+// GetInt64 reads an integer from the environment, falling back to the given
+// default when the variable is unset or invalid.
+func GetInt64(key string, fallback int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
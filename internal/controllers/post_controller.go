@@ -1,204 +0,0 @@
-package controllers
-
-import (
-	"bad_boyes/internal/models"
-	"bad_boyes/internal/services"
-	"net/http"
-	"strconv"
-
-	"github.com/gin-gonic/gin"
-)
-
-type PostController struct {
-	postService *services.PostService
-}
-
-func NewPostController(postService *services.PostService) *PostController {
-	return &PostController{
-		postService: postService,
-	}
-}
-
-func (c *PostController) CreatePost(ctx *gin.Context) {
-	userID := ctx.GetUint("user_id")
-	var req models.CreatePostRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	post, err := c.postService.CreatePost(userID, req)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	ctx.JSON(http.StatusCreated, post)
-}
-
-func (c *PostController) GetPost(ctx *gin.Context) {
-	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
-		return
-	}
-
-	post, err := c.postService.GetPost(uint(id))
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
-		return
-	}
-
-	ctx.JSON(http.StatusOK, post)
-}
-
-func (c *PostController) UpdatePost(ctx *gin.Context) {
-	userID := ctx.GetUint("user_id")
-	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
-		return
-	}
-
-	var req models.UpdatePostRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	post, err := c.postService.UpdatePost(userID, uint(id), req)
-	if err != nil {
-		if err.Error() == "unauthorized" {
-			ctx.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	ctx.JSON(http.StatusOK, post)
-}
-
-func (c *PostController) DeletePost(ctx *gin.Context) {
-	userID := ctx.GetUint("user_id")
-	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
-		return
-	}
-
-	if err := c.postService.DeletePost(userID, uint(id)); err != nil {
-		if err.Error() == "unauthorized" {
-			ctx.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	ctx.Status(http.StatusNoContent)
-}
-
-func (c *PostController) ListPosts(ctx *gin.Context) {
-	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
-
-	var userID *uint
-	if id, exists := ctx.Get("user_id"); exists {
-		uid := id.(uint)
-		userID = &uid
-	}
-
-	posts, total, err := c.postService.ListPosts(page, pageSize, userID)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	ctx.JSON(http.StatusOK, gin.H{
-		"data":  posts,
-		"total": total,
-		"page":  page,
-		"size":  pageSize,
-	})
-}
-
-func (c *PostController) CreateReport(ctx *gin.Context) {
-	userID := ctx.GetUint("user_id")
-	postID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
-		return
-	}
-
-	var req models.CreateReportRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	if err := c.postService.CreateReport(userID, uint(postID), req); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	ctx.Status(http.StatusCreated)
-}
-
-func (c *PostController) UpdateReportStatus(ctx *gin.Context) {
-	userID := ctx.GetUint("user_id")
-	reportID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid report id"})
-		return
-	}
-
-	var req struct {
-		Status string `json:"status" binding:"required,oneof=pending resolved rejected"`
-	}
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	if err := c.postService.UpdateReportStatus(userID, uint(reportID), req.Status); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	ctx.Status(http.StatusOK)
-}
-
-func (c *PostController) ListReports(ctx *gin.Context) {
-	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
-
-	reports, total, err := c.postService.ListReports(page, pageSize)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	ctx.JSON(http.StatusOK, gin.H{
-		"data":  reports,
-		"total": total,
-		"page":  page,
-		"size":  pageSize,
-	})
-}
-
-func (c *PostController) GetPostHistory(ctx *gin.Context) {
-	postID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
-		return
-	}
-
-	history, err := c.postService.GetPostHistory(uint(postID))
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	ctx.JSON(http.StatusOK, history)
-}
@@ -0,0 +1,302 @@
+// Package fieldcrypt encrypts individual database columns. Each value is
+// sealed with its own random data key, which is in turn sealed with a
+// versioned key from the key ring, so rotating the key ring only requires
+// re-wrapping values. Blind indexes allow equality lookups on encrypted
+// columns.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// prefix marks encrypted values; anything without it is legacy plaintext.
+const prefix = "enc:v"
+
+const (
+	keySize   = 32
+	nonceSize = 12
+	// wrappedKeySize is a sealed data key: nonce, key and GCM tag.
+	wrappedKeySize = nonceSize + keySize + 16
+)
+
+var (
+	ErrNoKeyRing     = errors.New("field encryption key ring is not configured")
+	ErrUnknownKey    = errors.New("value was encrypted with a key that is not in the key ring")
+	ErrMalformed     = errors.New("encrypted value is malformed")
+	ErrInvalidKeyCfg = errors.New("invalid field encryption key configuration")
+)
+
+// compromisedKeys are SHA-256 fingerprints of keys that were once published
+// as development defaults and must never protect real data.
+var compromisedKeys = map[string]bool{
+	"e2b699a039a72f77ba00680942d6fabbe0dedefef9085e6be615ac3a2fa9ab42": true,
+	"a5240d182c0e1e993c34aab3caf28cd027f66f1876cc07ed1d7eab42ff3bd0f5": true,
+}
+
+// KeyRing holds the key-encryption keys by version, the version used for new
+// values, and the separate key for blind indexes, which must never rotate.
+type KeyRing struct {
+	keys     map[uint32][]byte
+	active   uint32
+	indexKey []byte
+}
+
+// NewKeyRing builds a key ring. Every key, including indexKey, must be 32
+// bytes, and active must be one of the key versions.
+func NewKeyRing(keys map[uint32][]byte, active uint32, indexKey []byte) (*KeyRing, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("%w: active key version %d is not in the key ring", ErrInvalidKeyCfg, active)
+	}
+	for version, key := range keys {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("%w: key version %d must be %d bytes", ErrInvalidKeyCfg, version, keySize)
+		}
+	}
+	if len(indexKey) != keySize {
+		return nil, fmt.Errorf("%w: blind index key must be %d bytes", ErrInvalidKeyCfg, keySize)
+	}
+	return &KeyRing{keys: keys, active: active, indexKey: indexKey}, nil
+}
+
+// NewKeyRingFromEnv reads FIELD_ENCRYPTION_KEYS, a comma-separated list of
+// version:base64key pairs, FIELD_ENCRYPTION_ACTIVE_KEY, the version for new
+// values (the highest version when unset), and FIELD_BLIND_INDEX_KEY. It
+// fails when the keys are unset or one of them is known to be compromised.
+func NewKeyRingFromEnv() (*KeyRing, error) {
+	keys := make(map[uint32][]byte)
+	for _, entry := range strings.Split(os.Getenv("FIELD_ENCRYPTION_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rawVersion, encoded, ok := strings.Cut(entry, ":")
+		version, err := strconv.ParseUint(rawVersion, 10, 32)
+		if !ok || err != nil || version == 0 {
+			return nil, fmt.Errorf("%w: FIELD_ENCRYPTION_KEYS entries must look like 1:<base64 key>", ErrInvalidKeyCfg)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key version %d is not valid base64", ErrInvalidKeyCfg, version)
+		}
+		if compromised(key) {
+			return nil, fmt.Errorf("%w: key version %d is a published key and must be replaced", ErrInvalidKeyCfg, version)
+		}
+		keys[uint32(version)] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: FIELD_ENCRYPTION_KEYS is not set", ErrInvalidKeyCfg)
+	}
+
+	var active uint32
+	if raw := os.Getenv("FIELD_ENCRYPTION_ACTIVE_KEY"); raw != "" {
+		version, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: FIELD_ENCRYPTION_ACTIVE_KEY must be a key version", ErrInvalidKeyCfg)
+		}
+		active = uint32(version)
+	} else {
+		for version := range keys {
+			if version > active {
+				active = version
+			}
+		}
+	}
+
+	rawIndexKey := strings.TrimSpace(os.Getenv("FIELD_BLIND_INDEX_KEY"))
+	if rawIndexKey == "" {
+		return nil, fmt.Errorf("%w: FIELD_BLIND_INDEX_KEY is not set", ErrInvalidKeyCfg)
+	}
+	indexKey, err := base64.StdEncoding.DecodeString(rawIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: FIELD_BLIND_INDEX_KEY is not valid base64", ErrInvalidKeyCfg)
+	}
+	if compromised(indexKey) {
+		return nil, fmt.Errorf("%w: FIELD_BLIND_INDEX_KEY is a published key and must be replaced", ErrInvalidKeyCfg)
+	}
+	return NewKeyRing(keys, active, indexKey)
+}
+
+func compromised(key []byte) bool {
+	sum := sha256.Sum256(key)
+	return compromisedKeys[hex.EncodeToString(sum[:])]
+}
+
+// ActiveVersion is the key version new values are encrypted with.
+func (k *KeyRing) ActiveVersion() uint32 {
+	return k.active
+}
+
+// Versions lists the key versions in the ring, oldest first.
+func (k *KeyRing) Versions() []uint32 {
+	versions := make([]uint32, 0, len(k.keys))
+	for version := range k.keys {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+// Encrypt seals a value under a fresh data key wrapped with the active key.
+// The result is "enc:v<version>:" followed by base64 of the wrapped data
+// key, the nonce and the ciphertext.
+func (k *KeyRing) Encrypt(plaintext string) (string, error) {
+	header := prefix + strconv.FormatUint(uint64(k.active), 10) + ":"
+
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keys[k.active], dataKey, []byte(header))
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(dataKey, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	return header + base64.RawStdEncoding.EncodeToString(append(wrapped, sealed...)), nil
+}
+
+// Decrypt opens a value written by Encrypt. Values without the encrypted
+// prefix are returned unchanged, so rows written before encryption was
+// enabled stay readable until they are re-encrypted.
+func (k *KeyRing) Decrypt(value string) (string, error) {
+	version, header, body, ok := parse(value)
+	if !ok {
+		return value, nil
+	}
+	key, found := k.keys[version]
+	if !found {
+		return "", fmt.Errorf("%w (version %d)", ErrUnknownKey, version)
+	}
+
+	data, err := base64.RawStdEncoding.DecodeString(body)
+	if err != nil || len(data) < wrappedKeySize+nonceSize {
+		return "", ErrMalformed
+	}
+	dataKey, err := open(key, data[:wrappedKeySize], []byte(header))
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataKey, data[wrappedKeySize:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap moves a value to the active key by re-sealing only its data key,
+// leaving the encrypted data as it is. Plaintext values are encrypted and
+// values already under the active key are returned unchanged.
+func (k *KeyRing) Rewrap(value string) (string, error) {
+	version, header, body, ok := parse(value)
+	if !ok {
+		return k.Encrypt(value)
+	}
+	if version == k.active {
+		return value, nil
+	}
+	key, found := k.keys[version]
+	if !found {
+		return "", fmt.Errorf("%w (version %d)", ErrUnknownKey, version)
+	}
+
+	data, err := base64.RawStdEncoding.DecodeString(body)
+	if err != nil || len(data) < wrappedKeySize+nonceSize {
+		return "", ErrMalformed
+	}
+	dataKey, err := open(key, data[:wrappedKeySize], []byte(header))
+	if err != nil {
+		return "", err
+	}
+
+	newHeader := prefix + strconv.FormatUint(uint64(k.active), 10) + ":"
+	wrapped, err := seal(k.keys[k.active], dataKey, []byte(newHeader))
+	if err != nil {
+		return "", err
+	}
+	return newHeader + base64.RawStdEncoding.EncodeToString(append(wrapped, data[wrappedKeySize:]...)), nil
+}
+
+// Current reports whether a stored value is encrypted with the active key.
+func (k *KeyRing) Current(value string) bool {
+	version, _, _, ok := parse(value)
+	return ok && version == k.active
+}
+
+// BlindIndex returns a keyed hash of a value for equality lookups. Each
+// purpose, usually the indexed column, gets its own derived key so equal
+// values in different columns cannot be matched against each other.
+func (k *KeyRing) BlindIndex(purpose, value string) string {
+	derive := hmac.New(sha256.New, k.indexKey)
+	derive.Write([]byte(purpose))
+
+	mac := hmac.New(sha256.New, derive.Sum(nil))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsEncrypted reports whether a stored value has the encrypted format.
+func IsEncrypted(value string) bool {
+	_, _, _, ok := parse(value)
+	return ok
+}
+
+func parse(value string) (version uint32, header, body string, ok bool) {
+	if !strings.HasPrefix(value, prefix) {
+		return 0, "", "", false
+	}
+	rawVersion, body, found := strings.Cut(value[len(prefix):], ":")
+	parsed, err := strconv.ParseUint(rawVersion, 10, 32)
+	if !found || err != nil {
+		return 0, "", "", false
+	}
+	return uint32(parsed), prefix + rawVersion + ":", body, true
+}
+
+func seal(key, plaintext, additional []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(key, sealed, additional []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < nonceSize {
+		return nil, ErrMalformed
+	}
+	plaintext, err := aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], additional)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,89 @@
+package fieldcrypt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"gorm.io/gorm/schema"
+)
+
+// ring is the key ring used by the GORM serializer and BlindIndex.
+var ring atomic.Pointer[KeyRing]
+
+func init() {
+	schema.RegisterSerializer("encrypted", Serializer{})
+}
+
+// Use sets the key ring for encrypted columns. It must be called before the
+// database is used.
+func Use(keyRing *KeyRing) {
+	ring.Store(keyRing)
+}
+
+// BlindIndex hashes a value with the key ring set by Use.
+func BlindIndex(purpose, value string) (string, error) {
+	keyRing := ring.Load()
+	if keyRing == nil {
+		return "", ErrNoKeyRing
+	}
+	return keyRing.BlindIndex(purpose, value), nil
+}
+
+// Serializer encrypts string and *string fields tagged with
+// `gorm:"serializer:encrypted"`. Nil pointers are stored as NULL.
+type Serializer struct{}
+
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	fieldValue := reflect.New(field.FieldType).Elem()
+	if dbValue != nil {
+		keyRing := ring.Load()
+		if keyRing == nil {
+			return ErrNoKeyRing
+		}
+
+		var stored string
+		switch v := dbValue.(type) {
+		case []byte:
+			stored = string(v)
+		case string:
+			stored = v
+		default:
+			return fmt.Errorf("fieldcrypt: cannot decrypt %T in %s", dbValue, field.Name)
+		}
+		plaintext, err := keyRing.Decrypt(stored)
+		if err != nil {
+			return fmt.Errorf("fieldcrypt: %s: %w", field.Name, err)
+		}
+
+		if fieldValue.Kind() == reflect.Ptr {
+			fieldValue.Set(reflect.New(fieldValue.Type().Elem()))
+			fieldValue.Elem().SetString(plaintext)
+		} else {
+			fieldValue.SetString(plaintext)
+		}
+	}
+	return field.Set(ctx, dst, fieldValue.Interface())
+}
+
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var plaintext string
+	switch v := fieldValue.(type) {
+	case string:
+		plaintext = v
+	case *string:
+		if v == nil {
+			return nil, nil
+		}
+		plaintext = *v
+	default:
+		return nil, fmt.Errorf("fieldcrypt: cannot encrypt %T in %s", fieldValue, field.Name)
+	}
+
+	keyRing := ring.Load()
+	if keyRing == nil {
+		return nil, ErrNoKeyRing
+	}
+	return keyRing.Encrypt(plaintext)
+}
@@ -0,0 +1,121 @@
+// Package geo holds coordinate helpers used for location queries and the
+// geocoder interface that turns addresses into coordinates.
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const earthRadiusKm = 6371.0088
+
+var (
+	ErrInvalidCoordinates = errors.New("latitude must be between -90 and 90 and longitude between -180 and 180")
+	ErrInvalidBox         = errors.New("bounding box minimum latitude must not be above its maximum")
+)
+
+type Coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+func (c Coordinates) Valid() bool {
+	return c.Latitude >= -90 && c.Latitude <= 90 &&
+		c.Longitude >= -180 && c.Longitude <= 180 &&
+		!math.IsNaN(c.Latitude) && !math.IsNaN(c.Longitude)
+}
+
+// Box is an area between two latitudes and two longitudes. Boxes never
+// cross the antimeridian; Split returns two boxes for areas that do.
+type Box struct {
+	MinLat, MinLng, MaxLat, MaxLng float64
+}
+
+// NewBox validates the corners of a map view. A minimum longitude above the
+// maximum means the view crosses the antimeridian.
+func NewBox(minLat, minLng, maxLat, maxLng float64) ([]Box, error) {
+	if !(Coordinates{minLat, minLng}).Valid() || !(Coordinates{maxLat, maxLng}).Valid() {
+		return nil, ErrInvalidCoordinates
+	}
+	if minLat > maxLat {
+		return nil, ErrInvalidBox
+	}
+	if minLng > maxLng {
+		return []Box{
+			{MinLat: minLat, MinLng: minLng, MaxLat: maxLat, MaxLng: 180},
+			{MinLat: minLat, MinLng: -180, MaxLat: maxLat, MaxLng: maxLng},
+		}, nil
+	}
+	return []Box{{MinLat: minLat, MinLng: minLng, MaxLat: maxLat, MaxLng: maxLng}}, nil
+}
+
+// BoxAround returns the boxes covering every point within radiusKm of the
+// center, used to narrow a radius search before measuring distances.
+func BoxAround(center Coordinates, radiusKm float64) []Box {
+	angle := radiusKm / earthRadiusKm
+	deltaLat := angle * 180 / math.Pi
+	minLat := math.Max(center.Latitude-deltaLat, -90)
+	maxLat := math.Min(center.Latitude+deltaLat, 90)
+
+	// Near the poles every longitude is within reach
+	cos := math.Cos(center.Latitude * math.Pi / 180)
+	if minLat == -90 || maxLat == 90 || cos < 1e-9 {
+		return []Box{{MinLat: minLat, MinLng: -180, MaxLat: maxLat, MaxLng: 180}}
+	}
+
+	// The circle is widest poleward of its center, so the longitude span is
+	// asin(sin(angle)/cos(lat)) rather than the span at the center latitude
+	sinLng := math.Sin(angle) / cos
+	if angle >= math.Pi/2 || sinLng >= 1 {
+		return []Box{{MinLat: minLat, MinLng: -180, MaxLat: maxLat, MaxLng: 180}}
+	}
+	deltaLng := math.Asin(sinLng) * 180 / math.Pi
+
+	minLng := center.Longitude - deltaLng
+	maxLng := center.Longitude + deltaLng
+	switch {
+	case minLng < -180:
+		return []Box{
+			{MinLat: minLat, MinLng: minLng + 360, MaxLat: maxLat, MaxLng: 180},
+			{MinLat: minLat, MinLng: -180, MaxLat: maxLat, MaxLng: maxLng},
+		}
+	case maxLng > 180:
+		return []Box{
+			{MinLat: minLat, MinLng: minLng, MaxLat: maxLat, MaxLng: 180},
+			{MinLat: minLat, MinLng: -180, MaxLat: maxLat, MaxLng: maxLng - 360},
+		}
+	}
+	return []Box{{MinLat: minLat, MinLng: minLng, MaxLat: maxLat, MaxLng: maxLng}}
+}
+
+// WKT renders the box as a polygon with longitude as X and latitude as Y,
+// the axis order of the posts.location column.
+func (b Box) WKT() string {
+	return fmt.Sprintf("POLYGON((%[2]f %[1]f, %[4]f %[1]f, %[4]f %[3]f, %[2]f %[3]f, %[2]f %[1]f))",
+		b.MinLat, b.MinLng, b.MaxLat, b.MaxLng)
+}
+
+// DistanceKm is the great-circle distance between two points.
+func DistanceKm(a, b Coordinates) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// normalizeAddress makes lookups insensitive to case, punctuation and
+// spacing.
+func normalizeAddress(address string) string {
+	address = strings.ToLower(address)
+	address = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(",.;#", r) {
+			return ' '
+		}
+		return r
+	}, address)
+	return strings.Join(strings.Fields(address), " ")
+}
@@ -0,0 +1,141 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// degreeKm is the radius that spans exactly one degree of latitude.
+const degreeKm = earthRadiusKm * math.Pi / 180
+
+func boxesEqual(a, b []Box) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		for _, d := range []float64{
+			a[i].MinLat - b[i].MinLat, a[i].MinLng - b[i].MinLng,
+			a[i].MaxLat - b[i].MaxLat, a[i].MaxLng - b[i].MaxLng,
+		} {
+			if math.Abs(d) > 1e-6 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (b Box) contains(c Coordinates) bool {
+	return c.Latitude >= b.MinLat && c.Latitude <= b.MaxLat &&
+		c.Longitude >= b.MinLng && c.Longitude <= b.MaxLng
+}
+
+// destination is the point distanceKm from start in the given bearing.
+func destination(start Coordinates, bearing, distanceKm float64) Coordinates {
+	lat1 := start.Latitude * math.Pi / 180
+	lng1 := start.Longitude * math.Pi / 180
+	d := distanceKm / earthRadiusKm
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(bearing))
+	lng2 := lng1 + math.Atan2(math.Sin(bearing)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	lng := math.Mod(lng2*180/math.Pi+540, 360) - 180
+	return Coordinates{Latitude: lat2 * 180 / math.Pi, Longitude: lng}
+}
+
+func TestBoxAround(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		center   Coordinates
+		radiusKm float64
+		want     []Box
+	}{
+		{"equator", Coordinates{0, 0}, degreeKm, []Box{{-1, -1, 1, 1}}},
+		{"mid latitude widens longitude", Coordinates{60, 10}, degreeKm, []Box{{59, 10 - 2.00030478, 61, 10 + 2.00030478}}},
+		{"southern hemisphere", Coordinates{-60, -10}, degreeKm, []Box{{-61, -10 - 2.00030478, -59, -10 + 2.00030478}}},
+		{"crosses the antimeridian eastwards", Coordinates{0, 179.5}, degreeKm, []Box{
+			{-1, 178.5, 1, 180},
+			{-1, -180, 1, -179.5},
+		}},
+		{"crosses the antimeridian westwards", Coordinates{0, -179.5}, degreeKm, []Box{
+			{-1, 179.5, 1, 180},
+			{-1, -180, 1, -178.5},
+		}},
+		{"touches the antimeridian", Coordinates{0, 179}, degreeKm, []Box{{-1, 178, 1, 180}}},
+		{"reaches the north pole", Coordinates{89.5, 0}, degreeKm, []Box{{88.5, -180, 90, 180}}},
+		{"reaches the south pole", Coordinates{-89.5, 30}, degreeKm, []Box{{-90, -180, -88.5, 180}}},
+		{"at the north pole", Coordinates{90, 0}, degreeKm, []Box{{89, -180, 90, 180}}},
+		{"radius wider than the world", Coordinates{0, 0}, 200 * degreeKm, []Box{{-90, -180, 90, 180}}},
+		{"zero radius", Coordinates{45, 45}, 0, []Box{{45, 45, 45, 45}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := BoxAround(tc.center, tc.radiusKm)
+			if !boxesEqual(got, tc.want) {
+				t.Fatalf("BoxAround(%v, %v) = %v, want %v", tc.center, tc.radiusKm, got, tc.want)
+			}
+			for _, box := range got {
+				if box.MinLng < -180 || box.MaxLng > 180 || box.MinLng > box.MaxLng || box.MinLat > box.MaxLat {
+					t.Errorf("box %v crosses the antimeridian or is inverted", box)
+				}
+			}
+		})
+	}
+}
+
+// TestBoxAroundCoversCircle checks that every point on the search circle
+// falls inside one of the boxes, including circles that wrap around the
+// antimeridian or come close to a pole.
+func TestBoxAroundCoversCircle(t *testing.T) {
+	for _, center := range []Coordinates{
+		{0, 0}, {51.5, -0.1}, {-33.9, 151.2}, {0, 179.9}, {-17, -179.8},
+		{65, 179}, {-70, -179.5}, {85, 100}, {88.9, -45},
+	} {
+		for _, radiusKm := range []float64{1, 50, 500} {
+			boxes := BoxAround(center, radiusKm)
+			for bearing := 0.0; bearing < 2*math.Pi; bearing += math.Pi / 36 {
+				point := destination(center, bearing, radiusKm*0.999)
+				covered := false
+				for _, box := range boxes {
+					covered = covered || box.contains(point)
+				}
+				if !covered {
+					t.Errorf("BoxAround(%v, %v) = %v misses %v", center, radiusKm, boxes, point)
+				}
+			}
+		}
+	}
+}
+
+func TestNewBox(t *testing.T) {
+	for _, tc := range []struct {
+		name                           string
+		minLat, minLng, maxLat, maxLng float64
+		want                           []Box
+		err                            error
+	}{
+		{"plain view", 50, -1, 52, 1, []Box{{50, -1, 52, 1}}, nil},
+		{"single point", 10, 20, 10, 20, []Box{{10, 20, 10, 20}}, nil},
+		{"whole world", -90, -180, 90, 180, []Box{{-90, -180, 90, 180}}, nil},
+		{"wraps around the antimeridian", -20, 170, -10, -170, []Box{
+			{-20, 170, -10, 180},
+			{-20, -180, -10, -170},
+		}, nil},
+		{"starts on the antimeridian", 0, 180, 10, -170, []Box{
+			{0, 180, 10, 180},
+			{0, -180, 10, -170},
+		}, nil},
+		{"minimum latitude above maximum", 10, 0, -10, 5, nil, ErrInvalidBox},
+		{"latitude out of range", -91, 0, 10, 5, nil, ErrInvalidCoordinates},
+		{"longitude out of range", 0, 0, 10, 181, nil, ErrInvalidCoordinates},
+		{"not a number", math.NaN(), 0, 10, 5, nil, ErrInvalidCoordinates},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewBox(tc.minLat, tc.minLng, tc.maxLat, tc.maxLng)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("NewBox error = %v, want %v", err, tc.err)
+			}
+			if !boxesEqual(got, tc.want) {
+				t.Fatalf("NewBox = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var ErrAddressNotFound = errors.New("address could not be geocoded")
+
+// Geocoder resolves a free-text address to coordinates.
+type Geocoder interface {
+	Geocode(ctx context.Context, address string) (Coordinates, error)
+}
+
+// StaticGeocoder looks addresses up in a fixed table. It works offline and
+// is meant for development, tests and small deployments with known places.
+type StaticGeocoder struct {
+	places map[string]Coordinates
+}
+
+func NewStaticGeocoder(places map[string]Coordinates) *StaticGeocoder {
+	normalized := make(map[string]Coordinates, len(places))
+	for address, coords := range places {
+		normalized[normalizeAddress(address)] = coords
+	}
+	return &StaticGeocoder{places: normalized}
+}
+
+// LoadStaticGeocoder reads a JSON object mapping addresses to
+// {"latitude": .., "longitude": ..}.
+func LoadStaticGeocoder(path string) (*StaticGeocoder, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var places map[string]Coordinates
+	if err := json.Unmarshal(data, &places); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for address, coords := range places {
+		if !coords.Valid() {
+			return nil, fmt.Errorf("%s: invalid coordinates for %q", path, address)
+		}
+	}
+	return NewStaticGeocoder(places), nil
+}
+
+func (g *StaticGeocoder) Geocode(ctx context.Context, address string) (Coordinates, error) {
+	coords, ok := g.places[normalizeAddress(address)]
+	if !ok {
+		return Coordinates{}, ErrAddressNotFound
+	}
+	return coords, nil
+}
+
+// NewGeocoderFromEnv builds the geocoder selected by GEOCODER_DRIVER. It
+// returns nil when geocoding is disabled ("" or "none"); the "static" driver
+// reads its table from GEOCODER_STATIC_FILE.
+func NewGeocoderFromEnv() (Geocoder, error) {
+	switch os.Getenv("GEOCODER_DRIVER") {
+	case "", "none":
+		return nil, nil
+	case "static":
+		path := os.Getenv("GEOCODER_STATIC_FILE")
+		if path == "" {
+			return nil, errors.New("GEOCODER_STATIC_FILE is required for the static geocoder")
+		}
+		geocoder, err := LoadStaticGeocoder(path)
+		if err != nil {
+			return nil, err
+		}
+		return geocoder, nil
+	default:
+		return nil, fmt.Errorf("unknown GEOCODER_DRIVER %q", os.Getenv("GEOCODER_DRIVER"))
+	}
+}
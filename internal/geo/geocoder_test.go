@@ -0,0 +1,85 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	for _, tc := range []struct {
+		address string
+		want    string
+	}{
+		{"10 Downing Street, London", "10 downing street london"},
+		{"  10  DOWNING   Street ;London. ", "10 downing street london"},
+		{"Flat #4, 1 High St.", "flat 4 1 high st"},
+		{"10\tDowning\nStreet", "10 downing street"},
+		{"Straße 5, München", "straße 5 münchen"},
+		{"", ""},
+		{" ,.;# ", ""},
+	} {
+		if got := normalizeAddress(tc.address); got != tc.want {
+			t.Errorf("normalizeAddress(%q) = %q, want %q", tc.address, got, tc.want)
+		}
+	}
+}
+
+func TestStaticGeocoder(t *testing.T) {
+	london := Coordinates{Latitude: 51.5034, Longitude: -0.1276}
+	geocoder := NewStaticGeocoder(map[string]Coordinates{
+		"10 Downing Street, London": london,
+	})
+
+	for _, tc := range []struct {
+		address string
+		want    Coordinates
+		err     error
+	}{
+		{"10 Downing Street, London", london, nil},
+		{"10 downing street london", london, nil},
+		{" 10  DOWNING STREET;  London. ", london, nil},
+		{"10 Downing Street", Coordinates{}, ErrAddressNotFound},
+		{"11 Downing Street, London", Coordinates{}, ErrAddressNotFound},
+		{"", Coordinates{}, ErrAddressNotFound},
+	} {
+		got, err := geocoder.Geocode(context.Background(), tc.address)
+		if !errors.Is(err, tc.err) || got != tc.want {
+			t.Errorf("Geocode(%q) = %v, %v; want %v, %v", tc.address, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestLoadStaticGeocoder(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	geocoder, err := LoadStaticGeocoder(write("places.json", `{"Main St. 1, Springfield": {"latitude": 39.8, "longitude": -89.6}}`))
+	if err != nil {
+		t.Fatalf("LoadStaticGeocoder: %v", err)
+	}
+	got, err := geocoder.Geocode(context.Background(), "main st 1 springfield")
+	if want := (Coordinates{39.8, -89.6}); err != nil || got != want {
+		t.Fatalf("Geocode = %v, %v; want %v", got, err, want)
+	}
+
+	for name, content := range map[string]string{
+		"invalid.json": `{"North Pole": {"latitude": 91, "longitude": 0}}`,
+		"broken.json":  `{"North Pole": `,
+	} {
+		if _, err := LoadStaticGeocoder(write(name, content)); err == nil {
+			t.Errorf("LoadStaticGeocoder(%s) succeeded, want an error", name)
+		}
+	}
+	if _, err := LoadStaticGeocoder(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadStaticGeocoder of a missing file succeeded")
+	}
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"bad_boyes/internal/media"
+	"bad_boyes/internal/services"
+	"bad_boyes/internal/storage"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead allows for the multipart headers around the file.
+const multipartOverhead = 1 << 20
+
+type AttachmentHandler struct {
+	attachmentService *services.AttachmentService
+}
+
+func NewAttachmentHandler(attachmentService *services.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentService: attachmentService,
+	}
+}
+
+// UploadAttachment expects a multipart form with the file in the "file" field.
+func (h *AttachmentHandler) UploadAttachment(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.attachmentService.MaxSize()+multipartOverhead)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondAttachmentError(c, services.ErrAttachmentTooLarge)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a file is required in the \"file\" field"})
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.attachmentService.UploadAttachment(userID, uint(postID), header.Filename, file)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+func (h *AttachmentHandler) ListAttachments(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
+		return
+	}
+
+	attachments, err := h.attachmentService.ListAttachments(userID, uint(postID))
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
+	h.serve(c, false)
+}
+
+func (h *AttachmentHandler) DownloadThumbnail(c *gin.Context) {
+	h.serve(c, true)
+}
+
+func (h *AttachmentHandler) serve(c *gin.Context, thumbnail bool) {
+	userID := c.GetUint("user_id")
+	postID, attachmentID, ok := parseAttachmentParams(c)
+	if !ok {
+		return
+	}
+
+	attachment, reader, err := h.attachmentService.OpenAttachment(userID, postID, attachmentID, thumbnail)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+	defer reader.Close()
+
+	contentType, size := attachment.ContentType, attachment.Size
+	if thumbnail {
+		contentType, size = "image/jpeg", -1
+	}
+
+	// Only images are shown inline; everything else is downloaded
+	disposition := "attachment"
+	if media.IsImage(attachment.ContentType) {
+		disposition = "inline"
+	}
+
+	c.DataFromReader(http.StatusOK, size, contentType, reader, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "private, max-age=3600",
+	})
+}
+
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	postID, attachmentID, ok := parseAttachmentParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.attachmentService.DeleteAttachment(userID, postID, attachmentID); err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func parseAttachmentParams(c *gin.Context) (uint, uint, bool) {
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
+		return 0, 0, false
+	}
+	attachmentID, err := strconv.ParseUint(c.Param("attachment_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment id"})
+		return 0, 0, false
+	}
+	return uint(postID), uint(attachmentID), true
+}
+
+func respondAttachmentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, media.ErrUnsupportedType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, media.ErrCorruptImage), errors.Is(err, media.ErrImageTooLarge):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTooManyAttachments):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoThumbnail), errors.Is(err, storage.ErrObjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		respondPostError(c, err)
+	}
+}
@@ -3,6 +3,8 @@ package handler
 import (
 	"bad_boyes/internal/models"
 	"bad_boyes/internal/services"
+	"bytes"
+	"html/template"
 	"log"
 	"net/http"
 
@@ -82,6 +84,17 @@ func (h *AuthHandler) Login(ctx *gin.Context) {
 		return
 	}
 
+	if response.MFARequired {
+		log.Printf("Two-factor challenge issued for email: %s", req.Email)
+		ctx.JSON(http.StatusOK, gin.H{
+			"code":    200,
+			"status":  "success",
+			"message": "Two-factor authentication required",
+			"data":    response,
+		})
+		return
+	}
+
 	log.Printf("Login successful for email: %s", req.Email)
 	ctx.JSON(http.StatusOK, gin.H{
 		"code":    200,
@@ -145,3 +158,500 @@ func (h *AuthHandler) GetProfile(ctx *gin.Context) {
 		"data":    profile,
 	})
 }
+
+func (h *AuthHandler) RefreshToken(ctx *gin.Context) {
+	log.Printf("Received token refresh request from IP: %s", ctx.ClientIP())
+
+	var req models.RefreshTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Printf("Invalid refresh request from IP %s: %v", ctx.ClientIP(), err)
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": "Invalid request format",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	response, err := h.authService.RefreshToken(req.RefreshToken)
+	if err != nil {
+		log.Printf("Token refresh failed from IP %s: %v", ctx.ClientIP(), err)
+		switch err {
+		case services.ErrInvalidRefreshToken, services.ErrRefreshTokenExpired, services.ErrRefreshTokenReused, services.ErrUserNotFound:
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"status":  "error",
+				"message": "Token refresh failed",
+				"error":   err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"status":  "error",
+				"message": "Token refresh failed",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"status":  "success",
+		"message": "Token refreshed successfully",
+		"data":    response,
+	})
+}
+
+func (h *AuthHandler) Logout(ctx *gin.Context) {
+	userID := ctx.GetUint("user_id")
+	log.Printf("Received logout request for user ID: %d", userID)
+
+	var req models.LogoutRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"status":  "error",
+				"message": "Invalid request format",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	expiresAt := ctx.GetTime("token_expires_at")
+	if err := h.authService.Logout(userID, ctx.GetString("jti"), expiresAt, req.RefreshToken); err != nil {
+		log.Printf("Logout failed for user ID %d: %v", userID, err)
+		switch err {
+		case services.ErrInvalidRefreshToken:
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"status":  "error",
+				"message": "Logout failed",
+				"error":   err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"status":  "error",
+				"message": "Logout failed",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"status":  "success",
+		"message": "Logged out successfully",
+	})
+}
+
+func (h *AuthHandler) ForgotPassword(ctx *gin.Context) {
+	log.Printf("Received forgot password request from IP: %s", ctx.ClientIP())
+
+	var req models.ForgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": "Invalid request format",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.ForgotPassword(req.Email); err != nil {
+		log.Printf("Forgot password failed for email %s: %v", req.Email, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"status":  "error",
+			"message": "Failed to process password reset request",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"status":  "success",
+		"message": "If an account exists for this email, a password reset link has been sent",
+	})
+}
+
+// resetPasswordPage is the form the link in password reset emails opens. It
+// posts the token and the new password back to /password/reset.
+var resetPasswordPage = template.Must(template.New("reset").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Reset your password</title>
+</head>
+<body>
+<h1>Reset your password</h1>
+<form method="post" action="/password/reset">
+<input type="hidden" name="token" value="{{.}}">
+<label>New password <input type="password" name="password" minlength="6" required autocomplete="new-password"></label>
+<button type="submit">Reset password</button>
+</form>
+</body>
+</html>
+`))
+
+// ResetPasswordForm serves the page behind the link in password reset
+// emails. The token is only redeemed when the form is submitted.
+func (h *AuthHandler) ResetPasswordForm(ctx *gin.Context) {
+	token := ctx.Query("token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": "Invalid request format",
+			"error":   "token is required",
+		})
+		return
+	}
+
+	var page bytes.Buffer
+	if err := resetPasswordPage.Execute(&page, token); err != nil {
+		log.Printf("Failed to render password reset page: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	// The token is in the URL, keep it out of caches and referrers
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Referrer-Policy", "no-referrer")
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+// ResetPassword accepts a JSON body or the form served by ResetPasswordForm.
+func (h *AuthHandler) ResetPassword(ctx *gin.Context) {
+	log.Printf("Received password reset from IP: %s", ctx.ClientIP())
+
+	var req models.ResetPasswordRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": "Invalid request format",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.ResetPassword(req.Token, req.Password); err != nil {
+		log.Printf("Password reset failed from IP %s: %v", ctx.ClientIP(), err)
+		switch err {
+		case services.ErrInvalidResetToken, services.ErrResetTokenExpired:
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"status":  "error",
+				"message": "Password reset failed",
+				"error":   err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"status":  "error",
+				"message": "Password reset failed",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"status":  "success",
+		"message": "Password has been reset successfully",
+	})
+}
+
+// VerifyEmail takes the token from a JSON body, or from the query string
+// when the link in the verification email is opened.
+func (h *AuthHandler) VerifyEmail(ctx *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": "Invalid request format",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.VerifyEmail(req.Token); err != nil {
+		switch err {
+		case services.ErrInvalidVerificationToken, services.ErrVerificationTokenExpired:
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"status":  "error",
+				"message": "Email verification failed",
+				"error":   err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"status":  "error",
+				"message": "Email verification failed",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"status":  "success",
+		"message": "Email verified successfully",
+	})
+}
+
+func (h *AuthHandler) ResendVerification(ctx *gin.Context) {
+	userID := ctx.GetUint("user_id")
+	log.Printf("Received verification resend request for user ID: %d", userID)
+
+	if err := h.authService.ResendVerificationEmail(userID); err != nil {
+		log.Printf("Verification resend failed for user ID %d: %v", userID, err)
+		switch err {
+		case services.ErrEmailAlreadyVerified:
+			ctx.JSON(http.StatusConflict, gin.H{
+				"code":    409,
+				"status":  "error",
+				"message": "Email already verified",
+				"error":   err.Error(),
+			})
+		case services.ErrVerificationCooldown:
+			ctx.JSON(http.StatusTooManyRequests, gin.H{
+				"code":    429,
+				"status":  "error",
+				"message": "Too many requests",
+				"error":   err.Error(),
+			})
+		case services.ErrUserNotFound:
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"status":  "error",
+				"message": "User not found",
+				"error":   err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"status":  "error",
+				"message": "Failed to resend verification email",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"status":  "success",
+		"message": "Verification email sent",
+	})
+}
+
+func (h *AuthHandler) LoginTwoFactor(ctx *gin.Context) {
+	log.Printf("Received two-factor login from IP: %s", ctx.ClientIP())
+
+	var req models.TwoFactorLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": "Invalid request format",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	response, err := h.authService.CompleteMFALogin(req.MFAToken, req.Code)
+	if err != nil {
+		log.Printf("Two-factor login failed from IP %s: %v", ctx.ClientIP(), err)
+		switch err {
+		case services.ErrInvalidMFAToken, services.ErrInvalidTwoFactorCode:
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"status":  "error",
+				"message": "Login failed",
+				"error":   err.Error(),
+			})
+		case services.ErrTooManyMFAAttempts:
+			ctx.JSON(http.StatusTooManyRequests, gin.H{
+				"code":    429,
+				"status":  "error",
+				"message": "Login failed",
+				"error":   err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"status":  "error",
+				"message": "Login failed",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"status":  "success",
+		"message": "Login successful",
+		"data":    response,
+	})
+}
+
+func (h *AuthHandler) EnrollTwoFactor(ctx *gin.Context) {
+	userID := ctx.GetUint("user_id")
+	log.Printf("Received 2FA enrollment request for user ID: %d", userID)
+
+	response, err := h.authService.EnrollTwoFactor(userID)
+	if err != nil {
+		h.twoFactorError(ctx, "Two-factor enrollment failed", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"status":  "success",
+		"message": "Scan the secret with your authenticator app and confirm with a code",
+		"data":    response,
+	})
+}
+
+func (h *AuthHandler) ConfirmTwoFactor(ctx *gin.Context) {
+	userID := ctx.GetUint("user_id")
+
+	var req models.TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": "Invalid request format",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	response, err := h.authService.ConfirmTwoFactor(userID, req.Code)
+	if err != nil {
+		h.twoFactorError(ctx, "Two-factor confirmation failed", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"status":  "success",
+		"message": "Two-factor authentication enabled, store your recovery codes safely",
+		"data":    response,
+	})
+}
+
+func (h *AuthHandler) DisableTwoFactor(ctx *gin.Context) {
+	userID := ctx.GetUint("user_id")
+
+	var req models.TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": "Invalid request format",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.DisableTwoFactor(userID, req.Code); err != nil {
+		h.twoFactorError(ctx, "Failed to disable two-factor authentication", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"status":  "success",
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(ctx *gin.Context) {
+	userID := ctx.GetUint("user_id")
+
+	var req models.TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": "Invalid request format",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	response, err := h.authService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		h.twoFactorError(ctx, "Failed to regenerate recovery codes", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"status":  "success",
+		"message": "Recovery codes regenerated",
+		"data":    response,
+	})
+}
+
+func (h *AuthHandler) twoFactorError(ctx *gin.Context, message string, err error) {
+	log.Printf("%s for user ID %d: %v", message, ctx.GetUint("user_id"), err)
+	switch err {
+	case services.ErrInvalidTwoFactorCode:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": message,
+			"error":   err.Error(),
+		})
+	case services.ErrTooManyMFAAttempts:
+		ctx.JSON(http.StatusTooManyRequests, gin.H{
+			"code":    429,
+			"status":  "error",
+			"message": message,
+			"error":   err.Error(),
+		})
+	case services.ErrTwoFactorAlreadyEnabled, services.ErrTwoFactorNotEnrolled:
+		ctx.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"status":  "error",
+			"message": message,
+			"error":   err.Error(),
+		})
+	case services.ErrUserNotFound:
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"status":  "error",
+			"message": "User not found",
+			"error":   err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"status":  "error",
+			"message": message,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// JWKS publishes the public signing keys in the standard JSON Web Key Set
+// format so other services can verify tokens.
+func (h *AuthHandler) JWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, h.authService.JWKS())
+}
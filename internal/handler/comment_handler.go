@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"bad_boyes/internal/models"
+	"bad_boyes/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommentHandler struct {
+	commentService *services.CommentService
+}
+
+func NewCommentHandler(commentService *services.CommentService) *CommentHandler {
+	return &CommentHandler{
+		commentService: commentService,
+	}
+}
+
+func (h *CommentHandler) ListComments(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	comments, total, err := h.commentService.ListComments(userID, uint(postID), page, pageSize)
+	if err != nil {
+		respondCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  comments,
+		"total": total,
+		"page":  page,
+		"size":  pageSize,
+	})
+}
+
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
+		return
+	}
+
+	var req models.CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := h.commentService.CreateComment(userID, uint(postID), req)
+	if err != nil {
+		respondCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	postID, commentID, ok := parseCommentParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := h.commentService.UpdateComment(userID, postID, commentID, req)
+	if err != nil {
+		respondCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	postID, commentID, ok := parseCommentParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.commentService.DeleteComment(userID, postID, commentID); err != nil {
+		respondCommentError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func parseCommentParams(c *gin.Context) (uint, uint, bool) {
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
+		return 0, 0, false
+	}
+	commentID, err := strconv.ParseUint(c.Param("comment_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid comment id"})
+		return 0, 0, false
+	}
+	return uint(postID), uint(commentID), true
+}
+
+func respondCommentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCommentsDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidParent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCommentNotEditable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		respondPostError(c, err)
+	}
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"bad_boyes/internal/pagination"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parsePageParams reads page and page_size, responding with 400 when they
+// are not positive integers. Page sizes above the maximum are capped.
+func parsePageParams(c *gin.Context) (int, int, bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": pagination.ErrInvalidPage.Error()})
+		return 0, 0, false
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(pagination.DefaultPageSize)))
+	if err != nil || pageSize < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": pagination.ErrInvalidSize.Error()})
+		return 0, 0, false
+	}
+	return page, pagination.ClampSize(pageSize), true
+}
+
+// cursorResponse is the body of a keyset-paginated list.
+func cursorResponse(data interface{}, cursors pagination.Cursors, pageSize int) gin.H {
+	return gin.H{
+		"data":        data,
+		"next_cursor": cursors.Next,
+		"prev_cursor": cursors.Prev,
+		"size":        pageSize,
+	}
+}
@@ -1,12 +1,17 @@
 package handler
 
 import (
+	"bad_boyes/internal/geo"
 	"bad_boyes/internal/models"
+	"bad_boyes/internal/pagination"
+	"bad_boyes/internal/phone"
 	"bad_boyes/internal/services"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type PostHandler struct {
@@ -29,7 +34,7 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 
 	post, err := h.postService.CreatePost(userID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondPostError(c, err)
 		return
 	}
 
@@ -43,12 +48,22 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 		return
 	}
 
-	post, err := h.postService.GetPost(uint(id))
+	post, err := h.postService.GetPost(c.GetUint("user_id"), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		respondPostError(c, err)
 		return
 	}
 
+	// The body is redacted for the caller, so shared caches must not serve
+	// it to anyone else
+	etag := h.postService.PostETag(c.GetUint("user_id"), post)
+	c.Header("ETag", etag)
+	c.Header("Vary", "Authorization")
+	c.Header("Cache-Control", "private")
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" && services.ETagMatches(ifNoneMatch, etag, true) {
+		c.Status(http.StatusNotModified)
+		return
+	}
 	c.JSON(http.StatusOK, post)
 }
 
@@ -66,16 +81,45 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	post, err := h.postService.UpdatePost(userID, uint(id), req)
+	post, err := h.postService.UpdatePost(userID, uint(id), req, c.GetHeader("If-Match"))
 	if err != nil {
-		if err.Error() == "unauthorized" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondPostError(c, err)
 		return
 	}
 
+	c.Header("ETag", h.postService.PostETag(userID, post))
+	c.JSON(http.StatusOK, post)
+}
+
+// PatchPost applies a JSON merge patch (RFC 7386) to a post. Bodies must be
+// sent as application/merge-patch+json; application/json is accepted too.
+func (h *PostHandler) PatchPost(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
+		return
+	}
+
+	switch c.ContentType() {
+	case "application/merge-patch+json", "application/json":
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "content type must be application/merge-patch+json"})
+		return
+	}
+	patch, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	post, err := h.postService.PatchPost(userID, uint(id), patch, c.GetHeader("If-Match"))
+	if err != nil {
+		respondPostError(c, err)
+		return
+	}
+
+	c.Header("ETag", h.postService.PostETag(userID, post))
 	c.JSON(http.StatusOK, post)
 }
 
@@ -87,21 +131,65 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
-	if err := h.postService.DeletePost(userID, uint(id)); err != nil {
-		if err.Error() == "unauthorized" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.postService.DeletePost(userID, uint(id), c.GetHeader("If-Match")); err != nil {
+		respondPostError(c, err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+func (h *PostHandler) RestorePost(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
+		return
+	}
+
+	post, err := h.postService.RestorePost(userID, uint(id))
+	if err != nil {
+		respondPostError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+func (h *PostHandler) ListTrash(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	page, pageSize, ok := parsePageParams(c)
+	if !ok {
+		return
+	}
+
+	posts, total, err := h.postService.ListTrash(userID, page, pageSize)
+	if err != nil {
+		respondPostError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  posts,
+		"total": total,
+		"page":  page,
+		"size":  pageSize,
+	})
+}
+
+// ListPosts pages with offsets by default, or with keyset cursors when the
+// cursor parameter is present (empty for the first page).
 func (h *PostHandler) ListPosts(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	page, pageSize, ok := parsePageParams(c)
+	if !ok {
+		return
+	}
+
+	var filter models.PostFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	var userID *uint
 	if id, exists := c.Get("user_id"); exists {
@@ -109,9 +197,19 @@ func (h *PostHandler) ListPosts(c *gin.Context) {
 		userID = &uid
 	}
 
-	posts, total, err := h.postService.ListPosts(page, pageSize, userID)
+	if token, cursorMode := c.GetQuery("cursor"); cursorMode {
+		posts, cursors, err := h.postService.ListPostsByCursor(filter, token, pageSize, userID)
+		if err != nil {
+			respondPostError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, cursorResponse(posts, cursors, pageSize))
+		return
+	}
+
+	posts, total, err := h.postService.ListPosts(filter, page, pageSize, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondPostError(c, err)
 		return
 	}
 
@@ -123,6 +221,87 @@ func (h *PostHandler) ListPosts(c *gin.Context) {
 	})
 }
 
+func (h *PostHandler) SearchPosts(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	page, pageSize, ok := parsePageParams(c)
+	if !ok {
+		return
+	}
+
+	results, total, err := h.postService.SearchPosts(userID, c.Query("q"), page, pageSize)
+	if err != nil {
+		respondPostError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  results,
+		"total": total,
+		"page":  page,
+		"size":  pageSize,
+	})
+}
+
+// NearbyPosts lists posts within radius_km of lat/lng, closest first.
+func (h *PostHandler) NearbyPosts(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var query models.NearbyQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, pageSize, ok := parsePageParams(c)
+	if !ok {
+		return
+	}
+
+	center := geo.Coordinates{Latitude: *query.Latitude, Longitude: *query.Longitude}
+	results, total, err := h.postService.NearbyPosts(userID, center, query.RadiusKm, page, pageSize)
+	if err != nil {
+		respondPostError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  results,
+		"total": total,
+		"page":  page,
+		"size":  pageSize,
+	})
+}
+
+// MapPosts lists the newest posts inside a map view. The optional limit
+// caps how many are returned; total counts all posts in the view.
+func (h *PostHandler) MapPosts(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var query models.MapQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+	}
+
+	posts, total, err := h.postService.MapPosts(userID, *query.MinLat, *query.MinLng, *query.MaxLat, *query.MaxLng, limit)
+	if err != nil {
+		respondPostError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  posts,
+		"total": total,
+	})
+}
+
 func (h *PostHandler) CreateReport(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -138,7 +317,7 @@ func (h *PostHandler) CreateReport(c *gin.Context) {
 	}
 
 	if err := h.postService.CreateReport(userID, uint(postID), req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondPostError(c, err)
 		return
 	}
 
@@ -162,7 +341,7 @@ func (h *PostHandler) UpdateReportStatus(c *gin.Context) {
 	}
 
 	if err := h.postService.UpdateReportStatus(userID, uint(reportID), req.Status); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondPostError(c, err)
 		return
 	}
 
@@ -170,12 +349,25 @@ func (h *PostHandler) UpdateReportStatus(c *gin.Context) {
 }
 
 func (h *PostHandler) ListReports(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	userID := c.GetUint("user_id")
+	page, pageSize, ok := parsePageParams(c)
+	if !ok {
+		return
+	}
+
+	if token, cursorMode := c.GetQuery("cursor"); cursorMode {
+		reports, cursors, err := h.postService.ListReportsByCursor(userID, token, pageSize)
+		if err != nil {
+			respondPostError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, cursorResponse(reports, cursors, pageSize))
+		return
+	}
 
-	reports, total, err := h.postService.ListReports(page, pageSize)
+	reports, total, err := h.postService.ListReports(userID, page, pageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondPostError(c, err)
 		return
 	}
 
@@ -194,11 +386,105 @@ func (h *PostHandler) GetPostHistory(c *gin.Context) {
 		return
 	}
 
-	history, err := h.postService.GetPostHistory(uint(postID))
+	// Without a cursor the whole history is returned, as before
+	if token, cursorMode := c.GetQuery("cursor"); cursorMode {
+		_, pageSize, ok := parsePageParams(c)
+		if !ok {
+			return
+		}
+		history, cursors, err := h.postService.GetPostHistoryByCursor(c.GetUint("user_id"), uint(postID), token, pageSize)
+		if err != nil {
+			respondPostError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, cursorResponse(history, cursors, pageSize))
+		return
+	}
+
+	history, err := h.postService.GetPostHistory(c.GetUint("user_id"), uint(postID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondPostError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, history)
 }
+
+// GetRevisionDiff returns the field-level changes between a revision and
+// the one before it, or the revision given in the against query parameter.
+func (h *PostHandler) GetRevisionDiff(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
+		return
+	}
+	revision, err := strconv.ParseUint(c.Param("rev"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return
+	}
+	var against uint64
+	if value := c.Query("against"); value != "" {
+		if against, err = strconv.ParseUint(value, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid against revision"})
+			return
+		}
+	}
+
+	diff, err := h.postService.GetRevisionDiff(c.GetUint("user_id"), uint(postID), uint(revision), uint(against))
+	if err != nil {
+		respondPostError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// RevertPost makes the content of an earlier revision the post's new
+// revision.
+func (h *PostHandler) RevertPost(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
+		return
+	}
+	revision, err := strconv.ParseUint(c.Param("rev"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return
+	}
+
+	post, err := h.postService.RevertPost(userID, uint(postID), uint(revision), c.GetHeader("If-Match"))
+	if err != nil {
+		respondPostError(c, err)
+		return
+	}
+
+	c.Header("ETag", h.postService.PostETag(userID, post))
+	c.JSON(http.StatusOK, post)
+}
+
+// respondPostError maps service errors to HTTP responses.
+func respondPostError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidSearchQuery), errors.Is(err, pagination.ErrInvalidCursor), errors.Is(err, services.ErrInvalidPatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.As(err, new(*services.FilterError)), errors.Is(err, services.ErrCategoryNotFound), errors.Is(err, services.ErrInvalidTag):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, geo.ErrInvalidCoordinates), errors.Is(err, geo.ErrInvalidBox), errors.Is(err, services.ErrIncompleteCoordinates), errors.Is(err, phone.ErrInvalidNumber):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrForbidden), errors.Is(err, services.ErrRestoreWindowExpired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "record not found"})
+	case errors.Is(err, services.ErrRevisionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPreconditionRequired):
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPreconditionFailed):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
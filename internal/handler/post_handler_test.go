@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"bad_boyes/internal/services"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRespondPostErrorPreconditions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{services.ErrPreconditionRequired, http.StatusPreconditionRequired},
+		{services.ErrPreconditionFailed, http.StatusPreconditionFailed},
+		{fmt.Errorf("update post 12: %w", services.ErrPreconditionFailed), http.StatusPreconditionFailed},
+	} {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		respondPostError(c, tc.err)
+		if recorder.Code != tc.want {
+			t.Errorf("respondPostError(%v) status = %d, want %d", tc.err, recorder.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package handler
+
+import (
+	"bad_boyes/internal/models"
+	"bad_boyes/internal/services"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleHandler struct {
+	roleService *services.RoleService
+}
+
+func NewRoleHandler(roleService *services.RoleService) *RoleHandler {
+	return &RoleHandler{
+		roleService: roleService,
+	}
+}
+
+func (h *RoleHandler) ListRoles(ctx *gin.Context) {
+	roles, err := h.roleService.ListRoles()
+	if err != nil {
+		h.respondError(ctx, "Failed to list roles", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":   200,
+		"status": "success",
+		"data":   roles,
+	})
+}
+
+func (h *RoleHandler) GetRole(ctx *gin.Context) {
+	roleID, ok := parseIDParam(ctx, "id", "invalid role id")
+	if !ok {
+		return
+	}
+
+	role, err := h.roleService.GetRole(roleID)
+	if err != nil {
+		h.respondError(ctx, "Failed to fetch role", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":   200,
+		"status": "success",
+		"data":   role,
+	})
+}
+
+func (h *RoleHandler) CreateRole(ctx *gin.Context) {
+	var req models.CreateRoleRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	role, err := h.roleService.CreateRole(ctx.GetUint("user_id"), req.Name, req.Description, req.ParentID)
+	if err != nil {
+		h.respondError(ctx, "Failed to create role", err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"code":    201,
+		"status":  "success",
+		"message": "Role created successfully",
+		"data":    role,
+	})
+}
+
+func (h *RoleHandler) UpdateRole(ctx *gin.Context) {
+	roleID, ok := parseIDParam(ctx, "id", "invalid role id")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateRoleRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	role, err := h.roleService.UpdateRole(ctx.GetUint("user_id"), roleID, req.Name, req.Description)
+	if err != nil {
+		h.respondError(ctx, "Failed to update role", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"status":  "success",
+		"message": "Role updated successfully",
+		"data":    role,
+	})
+}
+
+func (h *RoleHandler) SetRoleParent(ctx *gin.Context) {
+	roleID, ok := parseIDParam(ctx, "id", "invalid role id")
+	if !ok {
+		return
+	}
+
+	var req models.SetRoleParentRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	role, err := h.roleService.SetRoleParent(ctx.GetUint("user_id"), roleID, req.ParentID)
+	if err != nil {
+		h.respondError(ctx, "Failed to set parent role", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"status":  "success",
+		"message": "Parent role updated successfully",
+		"data":    role,
+	})
+}
+
+func (h *RoleHandler) DeleteRole(ctx *gin.Context) {
+	roleID, ok := parseIDParam(ctx, "id", "invalid role id")
+	if !ok {
+		return
+	}
+
+	if err := h.roleService.DeleteRole(ctx.GetUint("user_id"), roleID); err != nil {
+		h.respondError(ctx, "Failed to delete role", err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *RoleHandler) GetRolePermissions(ctx *gin.Context) {
+	roleID, ok := parseIDParam(ctx, "id", "invalid role id")
+	if !ok {
+		return
+	}
+
+	permissions, err := h.roleService.GetRolePermissions(roleID)
+	if err != nil {
+		h.respondError(ctx, "Failed to fetch role permissions", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":   200,
+		"status": "success",
+		"data":   permissions,
+	})
+}
+
+func (h *RoleHandler) AssignPermissionToRole(ctx *gin.Context) {
+	roleID, ok := parseIDParam(ctx, "id", "invalid role id")
+	if !ok {
+		return
+	}
+
+	var req models.AssignPermissionRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	if err := h.roleService.AssignPermissionToRole(ctx.GetUint("user_id"), roleID, req.PermissionID); err != nil {
+		h.respondError(ctx, "Failed to grant permission", err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"code":    201,
+		"status":  "success",
+		"message": "Permission granted successfully",
+	})
+}
+
+func (h *RoleHandler) RemovePermissionFromRole(ctx *gin.Context) {
+	roleID, ok := parseIDParam(ctx, "id", "invalid role id")
+	if !ok {
+		return
+	}
+	permissionID, ok := parseIDParam(ctx, "permission_id", "invalid permission id")
+	if !ok {
+		return
+	}
+
+	if err := h.roleService.RemovePermissionFromRole(ctx.GetUint("user_id"), roleID, permissionID); err != nil {
+		h.respondError(ctx, "Failed to revoke permission", err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *RoleHandler) ListPermissions(ctx *gin.Context) {
+	permissions, err := h.roleService.ListPermissions()
+	if err != nil {
+		h.respondError(ctx, "Failed to list permissions", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":   200,
+		"status": "success",
+		"data":   permissions,
+	})
+}
+
+func (h *RoleHandler) CreatePermission(ctx *gin.Context) {
+	var req models.CreatePermissionRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	permission, err := h.roleService.CreatePermission(ctx.GetUint("user_id"), req.Description, req.Resource, req.Action)
+	if err != nil {
+		h.respondError(ctx, "Failed to create permission", err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"code":    201,
+		"status":  "success",
+		"message": "Permission created successfully",
+		"data":    permission,
+	})
+}
+
+func (h *RoleHandler) UpdatePermission(ctx *gin.Context) {
+	permissionID, ok := parseIDParam(ctx, "id", "invalid permission id")
+	if !ok {
+		return
+	}
+
+	var req models.UpdatePermissionRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	permission, err := h.roleService.UpdatePermission(ctx.GetUint("user_id"), permissionID, req.Description)
+	if err != nil {
+		h.respondError(ctx, "Failed to update permission", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"status":  "success",
+		"message": "Permission updated successfully",
+		"data":    permission,
+	})
+}
+
+func (h *RoleHandler) DeletePermission(ctx *gin.Context) {
+	permissionID, ok := parseIDParam(ctx, "id", "invalid permission id")
+	if !ok {
+		return
+	}
+
+	if err := h.roleService.DeletePermission(ctx.GetUint("user_id"), permissionID); err != nil {
+		h.respondError(ctx, "Failed to delete permission", err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *RoleHandler) GetUserRoles(ctx *gin.Context) {
+	userID, ok := parseIDParam(ctx, "id", "invalid user id")
+	if !ok {
+		return
+	}
+
+	roles, err := h.roleService.GetUserRoles(userID)
+	if err != nil {
+		h.respondError(ctx, "Failed to fetch user roles", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":   200,
+		"status": "success",
+		"data":   roles,
+	})
+}
+
+func (h *RoleHandler) AssignRoleToUser(ctx *gin.Context) {
+	userID, ok := parseIDParam(ctx, "id", "invalid user id")
+	if !ok {
+		return
+	}
+
+	var req models.AssignRoleRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	if err := h.roleService.AssignRoleToUser(ctx.GetUint("user_id"), userID, req.RoleID); err != nil {
+		h.respondError(ctx, "Failed to assign role", err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"code":    201,
+		"status":  "success",
+		"message": "Role assigned successfully",
+	})
+}
+
+func (h *RoleHandler) RemoveRoleFromUser(ctx *gin.Context) {
+	userID, ok := parseIDParam(ctx, "id", "invalid user id")
+	if !ok {
+		return
+	}
+	roleID, ok := parseIDParam(ctx, "role_id", "invalid role id")
+	if !ok {
+		return
+	}
+
+	if err := h.roleService.RemoveRoleFromUser(ctx.GetUint("user_id"), userID, roleID); err != nil {
+		h.respondError(ctx, "Failed to remove role", err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *RoleHandler) respondError(ctx *gin.Context, message string, err error) {
+	log.Printf("%s: %v", message, err)
+
+	status := http.StatusInternalServerError
+	switch err {
+	case services.ErrInvalidRoleName, services.ErrInvalidPermission, services.ErrRoleCycle:
+		status = http.StatusBadRequest
+	case services.ErrRoleProtected:
+		status = http.StatusForbidden
+	case services.ErrRoleNotFound, services.ErrPermissionNotFound, services.ErrUserNotFound,
+		services.ErrRoleNotAssigned, services.ErrPermissionNotAssigned:
+		status = http.StatusNotFound
+	case services.ErrRoleAlreadyExists, services.ErrPermissionAlreadyExists,
+		services.ErrRoleAlreadyAssigned, services.ErrPermissionAlreadyAssigned:
+		status = http.StatusConflict
+	}
+
+	ctx.JSON(status, gin.H{
+		"code":    status,
+		"status":  "error",
+		"message": message,
+		"error":   err.Error(),
+	})
+}
+
+// parseIDParam reads a numeric path parameter, responding with 400 when it
+// is not a valid ID.
+func parseIDParam(ctx *gin.Context, name, message string) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param(name), 10, 32)
+	if err != nil || id == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": message,
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// bindJSON binds the request body, responding with 400 when it is invalid.
+func bindJSON(ctx *gin.Context, req interface{}) bool {
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": "Invalid request format",
+			"error":   err.Error(),
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"bad_boyes/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SubjectHandler struct {
+	subjectService *services.SubjectService
+}
+
+func NewSubjectHandler(subjectService *services.SubjectService) *SubjectHandler {
+	return &SubjectHandler{
+		subjectService: subjectService,
+	}
+}
+
+func (h *SubjectHandler) GetSubject(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subject id"})
+		return
+	}
+
+	profile, err := h.subjectService.GetSubjectProfile(c.GetUint("user_id"), uint(id))
+	if err != nil {
+		respondPostError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"bad_boyes/internal/models"
+	"bad_boyes/internal/services"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TaxonomyHandler struct {
+	taxonomyService *services.TaxonomyService
+}
+
+func NewTaxonomyHandler(taxonomyService *services.TaxonomyService) *TaxonomyHandler {
+	return &TaxonomyHandler{
+		taxonomyService: taxonomyService,
+	}
+}
+
+func (h *TaxonomyHandler) ListCategories(ctx *gin.Context) {
+	categories, err := h.taxonomyService.ListCategories()
+	if err != nil {
+		h.respondError(ctx, "Failed to list categories", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":   200,
+		"status": "success",
+		"data":   categories,
+	})
+}
+
+func (h *TaxonomyHandler) CreateCategory(ctx *gin.Context) {
+	var req models.CreateCategoryRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	category, err := h.taxonomyService.CreateCategory(ctx.GetUint("user_id"), req)
+	if err != nil {
+		h.respondError(ctx, "Failed to create category", err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"code":    201,
+		"status":  "success",
+		"message": "Category created successfully",
+		"data":    category,
+	})
+}
+
+func (h *TaxonomyHandler) UpdateCategory(ctx *gin.Context) {
+	categoryID, ok := parseIDParam(ctx, "id", "invalid category id")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCategoryRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	category, err := h.taxonomyService.UpdateCategory(ctx.GetUint("user_id"), categoryID, req)
+	if err != nil {
+		h.respondError(ctx, "Failed to update category", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"status":  "success",
+		"message": "Category updated successfully",
+		"data":    category,
+	})
+}
+
+func (h *TaxonomyHandler) DeleteCategory(ctx *gin.Context) {
+	categoryID, ok := parseIDParam(ctx, "id", "invalid category id")
+	if !ok {
+		return
+	}
+
+	if err := h.taxonomyService.DeleteCategory(ctx.GetUint("user_id"), categoryID); err != nil {
+		h.respondError(ctx, "Failed to delete category", err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *TaxonomyHandler) PopularTags(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": "limit must be an integer",
+		})
+		return
+	}
+
+	tags, err := h.taxonomyService.PopularTags(limit)
+	if err != nil {
+		h.respondError(ctx, "Failed to list popular tags", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":   200,
+		"status": "success",
+		"data":   tags,
+	})
+}
+
+func (h *TaxonomyHandler) respondError(ctx *gin.Context, message string, err error) {
+	log.Printf("%s: %v", message, err)
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrInvalidCategorySlug):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.ErrCategoryNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrCategoryAlreadyExists):
+		status = http.StatusConflict
+	}
+
+	ctx.JSON(status, gin.H{
+		"code":    status,
+		"status":  "error",
+		"message": message,
+		"error":   err.Error(),
+	})
+}
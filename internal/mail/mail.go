@@ -0,0 +1,83 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers outgoing email. Implementations must be safe for
+// concurrent use.
+type Sender interface {
+	Send(msg Message) error
+}
+
+// FileSender writes messages to a local file instead of delivering them,
+// which is enough for local development.
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSender(path string) (*FileSender, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return &FileSender{path: path}, nil
+}
+
+func (s *FileSender) Send(msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n----\n",
+		time.Now().Format(time.RFC1123Z), msg.To, msg.Subject, msg.Body)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Mail to %s written to %s: %s", msg.To, s.path, msg.Subject)
+	return nil
+}
+
+// LogSender writes messages to the standard logger.
+type LogSender struct{}
+
+func (LogSender) Send(msg Message) error {
+	log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// NewSenderFromEnv builds the sender selected by MAIL_DRIVER ("file" or
+// "log"). The file driver writes to MAIL_FILE_PATH, defaulting to
+// storages/mail/outbox.log.
+func NewSenderFromEnv() (Sender, error) {
+	switch os.Getenv("MAIL_DRIVER") {
+	case "log":
+		return LogSender{}, nil
+	case "", "file":
+		path := os.Getenv("MAIL_FILE_PATH")
+		if path == "" {
+			path = "storages/mail/outbox.log"
+		}
+		return NewFileSender(path)
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", os.Getenv("MAIL_DRIVER"))
+	}
+}
@@ -0,0 +1,236 @@
+// Package media inspects and sanitises uploaded files: content sniffing,
+// metadata stripping and thumbnails for images.
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	_ "image/gif" // register the GIF decoder
+	"image/jpeg"
+	_ "image/png" // register the PNG decoder
+	"net/http"
+)
+
+const (
+	// MaxPixels bounds decoded images to keep a small file from expanding
+	// into gigabytes of memory.
+	MaxPixels = 40_000_000
+
+	ThumbnailSize = 320
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported file type")
+	ErrImageTooLarge   = errors.New("image dimensions are too large")
+	ErrCorruptImage    = errors.New("image could not be decoded")
+)
+
+// allowedTypes are the sniffed content types accepted for upload, with the
+// file extension used when storing them.
+var allowedTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+// Sniff detects the content type from the data itself, ignoring whatever
+// the client claimed, and returns it with the extension to store it under.
+func Sniff(data []byte) (string, string, error) {
+	contentType := http.DetectContentType(data)
+	// DetectContentType adds parameters such as "; charset=utf-8"
+	if i := bytes.IndexByte([]byte(contentType), ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	ext, ok := allowedTypes[contentType]
+	if !ok {
+		return "", "", ErrUnsupportedType
+	}
+	return contentType, ext, nil
+}
+
+func IsImage(contentType string) bool {
+	return contentType == "image/jpeg" || contentType == "image/png" || contentType == "image/gif"
+}
+
+// Dimensions reads the size of an image without decoding it.
+func Dimensions(data []byte) (int, int, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, ErrCorruptImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return 0, 0, ErrImageTooLarge
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+// StripMetadata removes EXIF (including GPS), XMP, IPTC and comments from
+// JPEG and PNG files without re-encoding the pixels. Other types are
+// returned unchanged.
+func StripMetadata(contentType string, data []byte) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	default:
+		return data, nil
+	}
+}
+
+// stripJPEG drops APP1 (EXIF/XMP), APP13 (IPTC) and COM segments. The
+// image data after the start-of-scan marker is copied untouched. The EXIF
+// orientation goes with APP1, so photos taken sideways are stored as
+// encoded.
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, ErrCorruptImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	pos := 2
+	for {
+		if pos+4 > len(data) || data[pos] != 0xFF {
+			return nil, ErrCorruptImage
+		}
+		marker := data[pos+1]
+		if marker == 0xFF {
+			// fill byte
+			pos++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			// start of scan or end of image: the rest carries no metadata
+			return append(out, data[pos:]...), nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, ErrCorruptImage
+		}
+		if marker != 0xE1 && marker != 0xED && marker != 0xFE {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+}
+
+// pngMetadataChunks are ancillary chunks that can carry personal data.
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+	signature := []byte("\x89PNG\r\n\x1a\n")
+	if !bytes.HasPrefix(data, signature) {
+		return nil, ErrCorruptImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, signature...)
+	pos := len(signature)
+	for pos < len(data) {
+		if pos+12 > len(data) {
+			return nil, ErrCorruptImage
+		}
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, ErrCorruptImage
+		}
+		chunkType := string(data[pos+4 : pos+8])
+		if crc32.ChecksumIEEE(data[pos+4:end-4]) != binary.BigEndian.Uint32(data[end-4:]) {
+			return nil, ErrCorruptImage
+		}
+		if !pngMetadataChunks[chunkType] {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+	return out, nil
+}
+
+// Thumbnail scales an image to fit within ThumbnailSize pixels and encodes
+// it as JPEG. Transparent areas are filled with white.
+func Thumbnail(data []byte) ([]byte, error) {
+	if _, _, err := Dimensions(data); err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrCorruptImage
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > ThumbnailSize || height > ThumbnailSize {
+		if width >= height {
+			height = max(1, height*ThumbnailSize/width)
+			width = ThumbnailSize
+		} else {
+			width = max(1, width*ThumbnailSize/height)
+			height = ThumbnailSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	scale(dst, src)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// scale draws src over dst, averaging the source pixels that fall into
+// each destination pixel (a box filter), which is adequate for downscaling.
+func scale(dst *image.RGBA, src image.Image) {
+	sb := src.Bounds()
+	db := dst.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	dw, dh := db.Dx(), db.Dy()
+
+	for y := 0; y < dh; y++ {
+		y0 := sb.Min.Y + y*sh/dh
+		y1 := max(y0+1, sb.Min.Y+(y+1)*sh/dh)
+		for x := 0; x < dw; x++ {
+			x0 := sb.Min.X + x*sw/dw
+			x1 := max(x0+1, sb.Min.X+(x+1)*sw/dw)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			r, g, b, a = r/n, g/n, b/n, a/n
+
+			// Composite the premultiplied average over the white background
+			bg := 0xffff - a
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8((r + bg) >> 8),
+				G: uint8((g + bg) >> 8),
+				B: uint8((b + bg) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+}
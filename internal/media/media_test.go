@@ -0,0 +1,201 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// gpsLatitude is the latitude written into the EXIF fixtures, 51° 30' 26"
+// as TIFF rationals; it must not survive stripping.
+var gpsLatitude = []uint32{51, 1, 30, 1, 26, 1}
+
+// exifWithGPS builds a big-endian TIFF structure as stored in EXIF: IFD0
+// with a camera model and a pointer to a GPS IFD holding the latitude.
+func exifWithGPS() []byte {
+	var b bytes.Buffer
+	write := func(v interface{}) { binary.Write(&b, binary.BigEndian, v) }
+
+	b.WriteString("MM")
+	write(uint16(42))
+	write(uint32(8)) // IFD0 offset
+
+	// IFD0 at 8: Model (ASCII, inline) and GPSInfo pointer
+	write(uint16(2))
+	write([]uint16{0x0110, 2})
+	write(uint32(4))
+	b.WriteString("Cam\x00")
+	write([]uint16{0x8825, 4})
+	write(uint32(1))
+	write(uint32(38)) // GPS IFD offset
+	write(uint32(0))
+
+	// GPS IFD at 38: GPSLatitudeRef "N" and GPSLatitude pointing at 68
+	write(uint16(2))
+	write([]uint16{0x0001, 2})
+	write(uint32(2))
+	b.WriteString("N\x00\x00\x00")
+	write([]uint16{0x0002, 5})
+	write(uint32(3))
+	write(uint32(68))
+	write(uint32(0))
+
+	write(gpsLatitude)
+	return b.Bytes()
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], chunkType)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 8; y++ {
+			img.Set(x, y, color.RGBA{uint8(x * 16), uint8(y * 32), 128, 255})
+		}
+	}
+	return img
+}
+
+// gpsBytes is the encoded latitude, which identifies the GPS data in a file.
+func gpsBytes() []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, gpsLatitude)
+	return b.Bytes()
+}
+
+// jpegWithMetadata encodes a test image and inserts EXIF with GPS, XMP, IPTC
+// and a comment after the start-of-image marker. It also returns the JPEG
+// without them.
+func jpegWithMetadata(t *testing.T) ([]byte, []byte) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	plain := encoded.Bytes()
+
+	jfif := jpegSegment(0xE0, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00"))
+	var data []byte
+	data = append(data, plain[:2]...)
+	data = append(data, jfif...)
+	data = append(data, jpegSegment(0xE1, append([]byte("Exif\x00\x00"), exifWithGPS()...))...)
+	data = append(data, jpegSegment(0xE1, []byte(`http://ns.adobe.com/xap/1.0/`+"\x00"+`<x:xmpmeta exif:GPSLatitude="51,30.43N"/>`))...)
+	data = append(data, jpegSegment(0xED, []byte("Photoshop 3.0\x008BIM\x04\x04\x00\x00\x00\x00\x00\x00"))...)
+	data = append(data, 0xFF) // fill byte
+	data = append(data, jpegSegment(0xFE, []byte("taken at home"))...)
+	data = append(data, plain[2:]...)
+
+	want := append(append(append([]byte(nil), plain[:2]...), jfif...), plain[2:]...)
+	return data, want
+}
+
+func TestStripMetadataJPEG(t *testing.T) {
+	data, want := jpegWithMetadata(t)
+	if !bytes.Contains(data, gpsBytes()) {
+		t.Fatal("fixture has no GPS data")
+	}
+
+	stripped, err := StripMetadata("image/jpeg", data)
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	if !bytes.Equal(stripped, want) {
+		t.Fatalf("stripped JPEG differs from the image without metadata segments")
+	}
+	for _, leaked := range [][]byte{gpsBytes(), []byte("Exif"), []byte("GPSLatitude"), []byte("8BIM"), []byte("taken at home")} {
+		if bytes.Contains(stripped, leaked) {
+			t.Errorf("stripped JPEG still contains %q", leaked)
+		}
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		t.Fatalf("stripped JPEG does not decode: %v", err)
+	}
+	if img.Bounds() != testImage().Bounds() {
+		t.Fatalf("stripped JPEG is %v, want %v", img.Bounds(), testImage().Bounds())
+	}
+}
+
+func TestStripMetadataPNG(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	plain := encoded.Bytes()
+	// signature and IHDR
+	header := 8 + 12 + 13
+
+	var data []byte
+	data = append(data, plain[:header]...)
+	data = append(data, pngChunk("eXIf", exifWithGPS())...)
+	data = append(data, pngChunk("tEXt", []byte("Comment\x00taken at home"))...)
+	data = append(data, pngChunk("iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00<x:xmpmeta exif:GPSLatitude=\"51,30.43N\"/>"))...)
+	data = append(data, pngChunk("zTXt", []byte("Author\x00\x00x"))...)
+	data = append(data, pngChunk("tIME", []byte{0x07, 0xE9, 6, 3, 12, 0, 0})...)
+	data = append(data, plain[header:]...)
+
+	stripped, err := StripMetadata("image/png", data)
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	if !bytes.Equal(stripped, plain) {
+		t.Fatalf("stripped PNG differs from the image without metadata chunks")
+	}
+	if _, err := png.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Fatalf("stripped PNG does not decode: %v", err)
+	}
+}
+
+func TestStripMetadataRejectsCorruptImages(t *testing.T) {
+	jpegData, _ := jpegWithMetadata(t)
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	badCRC := append([]byte(nil), pngData.Bytes()...)
+	badCRC[8+12+13-1] ^= 0xFF
+
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		data        []byte
+	}{
+		{"empty JPEG", "image/jpeg", nil},
+		{"JPEG without SOI", "image/jpeg", jpegData[2:]},
+		{"truncated JPEG segment", "image/jpeg", jpegData[:30]},
+		{"JPEG segment past the end", "image/jpeg", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF, 0x00}},
+		{"PNG without signature", "image/png", pngData.Bytes()[8:]},
+		{"truncated PNG chunk", "image/png", pngData.Bytes()[:20]},
+		{"PNG with a bad CRC", "image/png", badCRC},
+	} {
+		if _, err := StripMetadata(tc.contentType, tc.data); !errors.Is(err, ErrCorruptImage) {
+			t.Errorf("%s: err = %v, want ErrCorruptImage", tc.name, err)
+		}
+	}
+}
+
+func TestStripMetadataLeavesOtherTypes(t *testing.T) {
+	data := []byte("%PDF-1.4 Exif GPS")
+	stripped, err := StripMetadata("application/pdf", data)
+	if err != nil || !bytes.Equal(stripped, data) {
+		t.Fatalf("StripMetadata(pdf) = %q, %v; want the data unchanged", stripped, err)
+	}
+}
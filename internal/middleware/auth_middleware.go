@@ -1,16 +1,16 @@
 package middleware
 
 import (
+	"bad_boyes/internal/services"
 	"log"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		log.Printf("Processing request: %s %s", ctx.Request.Method, ctx.Request.URL.Path)
 
@@ -43,27 +43,8 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Get the token
 		tokenString := parts[1]
 
-		// Get JWT secret from environment
-		jwtSecret := os.Getenv("JWT_SECRET")
-		if jwtSecret == "" {
-			log.Printf("JWT_SECRET environment variable is not set")
-			ctx.JSON(http.StatusInternalServerError, gin.H{
-				"code":    500,
-				"status":  "error",
-				"message": "Server configuration error",
-			})
-			ctx.Abort()
-			return
-		}
-
-		// Parse and validate the token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(jwtSecret), nil
-		})
-
+		// Parse and validate the token against the configured keys
+		claims, err := authService.ParseToken(tokenString)
 		if err != nil {
 			log.Printf("Token validation failed: %v", err)
 			ctx.JSON(http.StatusUnauthorized, gin.H{
@@ -76,22 +57,22 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Check if the token is valid
-		if !token.Valid {
-			log.Printf("Token is invalid")
+		// Only access tokens may be used to authenticate requests
+		if use, _ := claims["token_use"].(string); use != services.TokenUseAccess {
+			log.Printf("Rejected token with token_use %q", claims["token_use"])
 			ctx.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
 				"status":  "error",
-				"message": "Invalid token",
+				"message": "Invalid token claims",
 			})
 			ctx.Abort()
 			return
 		}
 
-		// Get claims from token
-		claims, ok := token.Claims.(jwt.MapClaims)
+		// Get user ID from claims
+		userID, ok := claims["user_id"].(float64)
 		if !ok {
-			log.Printf("Failed to get claims from token")
+			log.Printf("Failed to get user_id from claims")
 			ctx.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
 				"status":  "error",
@@ -101,10 +82,10 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Get user ID from claims
-		userID, ok := claims["user_id"].(float64)
-		if !ok {
-			log.Printf("Failed to get user_id from claims")
+		// Reject tokens that have been revoked by logout or reuse detection
+		jti, ok := claims["jti"].(string)
+		if !ok || jti == "" {
+			log.Printf("Failed to get jti from claims")
 			ctx.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
 				"status":  "error",
@@ -114,8 +95,34 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		revoked, err := authService.IsTokenRevoked(jti)
+		if err != nil {
+			log.Printf("Failed to check token revocation: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"status":  "error",
+				"message": "Failed to validate token",
+			})
+			ctx.Abort()
+			return
+		}
+		if revoked {
+			log.Printf("Token %s has been revoked", jti)
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"status":  "error",
+				"message": "Token has been revoked",
+			})
+			ctx.Abort()
+			return
+		}
+
 		// Set user ID in context
 		ctx.Set("user_id", uint(userID))
+		ctx.Set("jti", jti)
+		if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+			ctx.Set("token_expires_at", exp.Time)
+		}
 		ctx.Set("claims", claims)
 		log.Printf("User authenticated: ID=%d", uint(userID))
 
@@ -123,8 +130,25 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
-func AdminMiddleware() gin.HandlerFunc {
+// RequireMFA rejects admin sessions that were not started with a second
+// factor when REQUIRE_ADMIN_2FA is enabled.
+func RequireMFA(authService *services.AuthService) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		required, err := authService.MFARequired(ctx.GetUint("user_id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"status":  "error",
+				"message": "Failed to check two-factor requirement",
+			})
+			ctx.Abort()
+			return
+		}
+		if !required {
+			ctx.Next()
+			return
+		}
+
 		claims, exists := ctx.Get("claims")
 		if !exists {
 			ctx.JSON(http.StatusUnauthorized, gin.H{
@@ -136,12 +160,12 @@ func AdminMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		role, ok := claims.(jwt.MapClaims)["role"].(string)
-		if !ok || role != "admin" {
+		mfa, _ := claims.(jwt.MapClaims)["mfa"].(bool)
+		if !mfa {
 			ctx.JSON(http.StatusForbidden, gin.H{
 				"code":    403,
 				"status":  "error",
-				"message": "Admin access required",
+				"message": "Two-factor authentication required",
 			})
 			ctx.Abort()
 			return
@@ -32,3 +32,33 @@ func RequirePermission(roleService *services.RoleService, resource, action strin
 		c.Next()
 	}
 }
+
+// RequireAnyPermission passes when the user holds at least one of the given
+// actions on the resource. It is used in front of handlers whose service
+// makes the final, ownership-aware decision.
+func RequireAnyPermission(roleService *services.RoleService, resource string, actions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetUint("user_id")
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			c.Abort()
+			return
+		}
+
+		for _, action := range actions {
+			hasPermission, err := roleService.CheckPermission(userID, resource, action)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check permission"})
+				c.Abort()
+				return
+			}
+			if hasPermission {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		c.Abort()
+	}
+}
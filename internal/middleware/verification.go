@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"bad_boyes/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail blocks users who have not verified their email when
+// the REQUIRE_VERIFIED_EMAIL policy is enabled.
+func RequireVerifiedEmail(authService *services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetUint("user_id")
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			c.Abort()
+			return
+		}
+
+		if err := authService.CheckEmailVerified(userID); err != nil {
+			switch err {
+			case services.ErrEmailNotVerified:
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			case services.ErrUserNotFound:
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check email verification"})
+			}
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type Attachment struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	PostID       uint      `json:"post_id" gorm:"not null"`
+	UserID       uint      `json:"user_id,omitempty" gorm:"not null"`
+	FileName     string    `json:"file_name" gorm:"not null"`
+	ContentType  string    `json:"content_type" gorm:"not null"`
+	Size         int64     `json:"size" gorm:"not null"`
+	Width        int       `json:"width,omitempty"`
+	Height       int       `json:"height,omitempty"`
+	StorageKey   string    `json:"-" gorm:"not null;unique"`
+	ThumbnailKey *string   `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Set by the service for responses
+	URL          string `json:"url" gorm:"-"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty" gorm:"-"`
+}
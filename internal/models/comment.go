@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	CommentStatusVisible = "visible"
+	CommentStatusDeleted = "deleted" // deleted by its author
+	CommentStatusRemoved = "removed" // removed by a moderator
+)
+
+type Comment struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	PostID    uint       `json:"post_id" gorm:"not null"`
+	UserID    uint       `json:"user_id" gorm:"not null"`
+	ParentID  *uint      `json:"parent_id"`
+	Body      string     `json:"body" gorm:"not null"`
+	Status    string     `json:"status" gorm:"not null;default:'visible'"`
+	EditedAt  *time.Time `json:"edited_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	User      *User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Replies   []*Comment `json:"replies,omitempty" gorm:"-"`
+}
+
+type CreateCommentRequest struct {
+	Body     string `json:"body" binding:"required,max=5000"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body" binding:"required,max=5000"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Location is the spatial copy of a post's coordinates stored in
+// posts.location. It is write-only: reads use Latitude and Longitude.
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// GormValue writes the point with longitude as X and latitude as Y.
+func (l Location) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	return clause.Expr{
+		SQL:  "ST_GeomFromText(?, 0)",
+		Vars: []interface{}{fmt.Sprintf("POINT(%f %f)", l.Longitude, l.Latitude)},
+	}
+}
+
+// syncLocation keeps posts.location in step with the coordinates. Posts
+// without coordinates get POINT(0 0) because the spatial index needs a
+// non-null column.
+func (p *Post) syncLocation() {
+	p.Location = Location{}
+	if p.Latitude != nil && p.Longitude != nil {
+		p.Location = Location{Latitude: *p.Latitude, Longitude: *p.Longitude}
+	}
+}
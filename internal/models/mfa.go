@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// RecoveryCode is a single-use fallback for TOTP. Only the SHA-256 hash of
+// the code is stored.
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"size:64;not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// MFAFailure is a wrong code entered for an MFA login challenge, or with an
+// empty ChallengeID, when changing two-factor settings.
+type MFAFailure struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"not null"`
+	ChallengeID string    `json:"challenge_id" gorm:"size:64;not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorLoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
@@ -1,42 +1,209 @@
 package models
 
-import "time"
+import (
+	"bad_boyes/internal/fieldcrypt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
 
 type Post struct {
-	ID            uint      `json:"id" gorm:"primaryKey"`
-	UserID        uint      `json:"user_id" gorm:"not null"`
-	Title         string    `json:"title" gorm:"not null"`
-	Description   string    `json:"description" gorm:"not null"`
-	Address       string    `json:"address" gorm:"not null"`
-	ContactName   string    `json:"contact_name" gorm:"not null"`
-	MobileNumber  string    `json:"mobile_number" gorm:"not null"`
-	IncidentDate  Date      `json:"incident_date" gorm:"not null"`
-	Status        string    `json:"status" gorm:"not null;default:'active'"`
-	IsAnonymous   bool      `json:"is_anonymous" gorm:"default:false"`
-	Visibility    string    `json:"visibility" gorm:"not null;default:'public'"`
-	AllowComments bool      `json:"allow_comments" gorm:"default:true"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
-	User          User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	UserID           uint           `json:"user_id" gorm:"not null"`
+	Title            string         `json:"title" gorm:"not null"`
+	Description      string         `json:"description" gorm:"not null"`
+	Address          string         `json:"address" gorm:"not null;serializer:encrypted"`
+	ContactName      string         `json:"contact_name" gorm:"not null;serializer:encrypted"`
+	MobileNumber     string         `json:"mobile_number" gorm:"not null;serializer:encrypted"`
+	MobileNumberE164 *string        `json:"mobile_number_e164" gorm:"column:mobile_number_e164;serializer:encrypted"`
+	MobileIndex      *string        `json:"-" gorm:"column:mobile_number_e164_bidx"`
+	IncidentDate     Date           `json:"incident_date" gorm:"not null"`
+	Status           string         `json:"status" gorm:"not null;default:'active'"`
+	IsAnonymous      bool           `json:"is_anonymous" gorm:"default:false"`
+	Visibility       string         `json:"visibility" gorm:"not null;default:'public'"`
+	AllowComments    bool           `json:"allow_comments" gorm:"default:true"`
+	Version          uint           `json:"version" gorm:"not null;default:1"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	CategoryID       *uint          `json:"category_id"`
+	Latitude         *float64       `json:"latitude"`
+	Longitude        *float64       `json:"longitude"`
+	Location         Location       `json:"-" gorm:"type:point;->:false;<-"`
+	SubjectID        *uint          `json:"subject_id"`
+	User             *User          `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Category         *Category      `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+	Tags             []Tag          `json:"tags" gorm:"many2many:post_tags"`
+}
+
+// Blind index purposes; each indexed column hashes with its own key.
+const (
+	PostMobileIndex      = "posts.mobile_number_e164"
+	PostSearchTokenIndex = "post_search_tokens.token"
+	SubjectPhoneIndex    = "subjects.phone"
+	SubjectNameIndex     = "subjects.name_key"
+)
+
+func (p *Post) BeforeSave(tx *gorm.DB) error {
+	p.syncLocation()
+	return p.IndexMobileNumber()
+}
+
+// IndexMobileNumber sets the blind index used to look posts up by their
+// encrypted normalized mobile number.
+func (p *Post) IndexMobileNumber() error {
+	p.MobileIndex = nil
+	if p.MobileNumberE164 == nil {
+		return nil
+	}
+	index, err := fieldcrypt.BlindIndex(PostMobileIndex, *p.MobileNumberE164)
+	if err != nil {
+		return err
+	}
+	p.MobileIndex = &index
+	return nil
+}
+
+// Snapshot returns the current revision of a post as a history entry.
+// Tag names are sorted so snapshots compare equal regardless of tag order.
+func (p *Post) Snapshot() PostHistory {
+	revision := p.Version
+	tags := make([]string, len(p.Tags))
+	for i, tag := range p.Tags {
+		tags[i] = tag.Name
+	}
+	sort.Strings(tags)
+	return PostHistory{
+		PostID:        p.ID,
+		Revision:      &revision,
+		UserID:        p.UserID,
+		Title:         p.Title,
+		Description:   p.Description,
+		Address:       p.Address,
+		ContactName:   p.ContactName,
+		MobileNumber:  p.MobileNumber,
+		IncidentDate:  p.IncidentDate,
+		Status:        p.Status,
+		IsAnonymous:   p.IsAnonymous,
+		Visibility:    p.Visibility,
+		AllowComments: p.AllowComments,
+		CategoryID:    p.CategoryID,
+		Tags:          tags,
+		Latitude:      p.Latitude,
+		Longitude:     p.Longitude,
+	}
+}
+
+// FieldChange is a field that differs between two revisions of a post.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// RevisionDiff lists the fields that differ between two revisions of a
+// post. FromRevision 0 stands for the empty post before it was created.
+type RevisionDiff struct {
+	PostID       uint          `json:"post_id"`
+	FromRevision uint          `json:"from_revision"`
+	ToRevision   uint          `json:"to_revision"`
+	Changes      []FieldChange `json:"changes"`
+}
+
+// PostSearchToken is the blind index of one word of a post's address or
+// contact name. Ciphertext cannot be full-text indexed, so search looks up
+// the words of a query here instead. Only whole words match.
+type PostSearchToken struct {
+	PostID uint   `gorm:"primaryKey"`
+	Token  string `gorm:"primaryKey"`
 }
 
+// SearchTokens returns the search tokens of a post's encrypted fields.
+func (p *Post) SearchTokens() ([]string, error) {
+	return SearchTokens(p.Address + " " + p.ContactName)
+}
+
+// SearchWords splits text into lower-cased words, ignoring punctuation and
+// duplicates.
+func SearchWords(text string) []string {
+	seen := make(map[string]bool)
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// SearchTokens returns the blind indexes of the words in text.
+func SearchTokens(text string) ([]string, error) {
+	words := SearchWords(text)
+	tokens := make([]string, len(words))
+	for i, word := range words {
+		token, err := fieldcrypt.BlindIndex(PostSearchTokenIndex, word)
+		if err != nil {
+			return nil, err
+		}
+		tokens[i] = token
+	}
+	return tokens, nil
+}
+
+// PostSearchResult is a post matched by a full-text search, with its
+// relevance score and the matching fragments of each field.
+type PostSearchResult struct {
+	Post
+	Relevance  float64           `json:"relevance"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// PostNearbyResult is a post found by a radius search and its distance from
+// the search center.
+type PostNearbyResult struct {
+	Post
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// PostHistory is a snapshot of a post taken before an update. It holds
+// revision Revision of the post; EditorID is the user whose update replaced
+// it and ChangedFields what that update changed. Entries recorded before
+// revisions were introduced have no revision.
 type PostHistory struct {
 	ID            uint      `json:"id" gorm:"primaryKey"`
 	PostID        uint      `json:"post_id" gorm:"not null"`
+	Revision      *uint     `json:"revision"`
 	UserID        uint      `json:"user_id" gorm:"not null"`
+	EditorID      *uint     `json:"editor_id"`
 	Title         string    `json:"title" gorm:"not null"`
 	Description   string    `json:"description" gorm:"not null"`
-	Address       string    `json:"address" gorm:"not null"`
-	ContactName   string    `json:"contact_name" gorm:"not null"`
-	MobileNumber  string    `json:"mobile_number" gorm:"not null"`
+	Address       string    `json:"address" gorm:"not null;serializer:encrypted"`
+	ContactName   string    `json:"contact_name" gorm:"not null;serializer:encrypted"`
+	MobileNumber  string    `json:"mobile_number" gorm:"not null;serializer:encrypted"`
 	IncidentDate  Date      `json:"incident_date" gorm:"not null"`
 	Status        string    `json:"status" gorm:"not null"`
 	IsAnonymous   bool      `json:"is_anonymous"`
 	Visibility    string    `json:"visibility" gorm:"not null"`
 	AllowComments bool      `json:"allow_comments"`
+	CategoryID    *uint     `json:"category_id"`
+	Tags          []string  `json:"tags" gorm:"serializer:json"`
+	Latitude      *float64  `json:"latitude"`
+	Longitude     *float64  `json:"longitude"`
+	ChangedFields []string  `json:"changed_fields,omitempty" gorm:"serializer:json"`
 	CreatedAt     time.Time `json:"created_at"`
 }
 
+// TableName keeps GORM from pluralising the table to post_histories.
+func (PostHistory) TableName() string {
+	return "post_history"
+}
+
 type Report struct {
 	ID         uint      `json:"id" gorm:"primaryKey"`
 	PostID     uint      `json:"post_id" gorm:"not null"`
@@ -46,31 +213,101 @@ type Report struct {
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 	Post       Post      `json:"post,omitempty" gorm:"foreignKey:PostID"`
-	Reporter   User      `json:"reporter,omitempty" gorm:"foreignKey:ReporterID"`
+	Reporter   *User     `json:"reporter,omitempty" gorm:"foreignKey:ReporterID"`
 }
 
 type CreatePostRequest struct {
-	Title         string `json:"title" binding:"required"`
-	Description   string `json:"description" binding:"required"`
-	Address       string `json:"address" binding:"required"`
-	ContactName   string `json:"contact_name" binding:"required"`
-	MobileNumber  string `json:"mobile_number" binding:"required"`
-	IncidentDate  Date   `json:"incident_date" binding:"required"`
-	IsAnonymous   bool   `json:"is_anonymous"`
-	Visibility    string `json:"visibility" binding:"required,oneof=public private"`
-	AllowComments bool   `json:"allow_comments"`
+	Title         string   `json:"title" binding:"required"`
+	Description   string   `json:"description" binding:"required"`
+	Address       string   `json:"address" binding:"required"`
+	ContactName   string   `json:"contact_name" binding:"required"`
+	MobileNumber  string   `json:"mobile_number" binding:"required"`
+	IncidentDate  Date     `json:"incident_date" binding:"required"`
+	IsAnonymous   bool     `json:"is_anonymous"`
+	Visibility    string   `json:"visibility" binding:"required,oneof=public private"`
+	AllowComments bool     `json:"allow_comments"`
+	CategoryID    *uint    `json:"category_id"`
+	Tags          []string `json:"tags" binding:"max=10,dive,max=50"`
+	Latitude      *float64 `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude     *float64 `json:"longitude" binding:"omitempty,min=-180,max=180"`
 }
 
+// UpdatePostRequest leaves out fields that are empty or absent. It changes
+// the category when category_id is set (0 removes it) and replaces the tags
+// when tags is present (an empty list clears them). Coordinates are set
+// together; a new address without them is geocoded.
 type UpdatePostRequest struct {
-	Title         string `json:"title"`
-	Description   string `json:"description"`
-	Address       string `json:"address"`
-	ContactName   string `json:"contact_name"`
-	MobileNumber  string `json:"mobile_number"`
-	IncidentDate  Date   `json:"incident_date"`
-	IsAnonymous   bool   `json:"is_anonymous"`
-	Visibility    string `json:"visibility" binding:"omitempty,oneof=public private"`
-	AllowComments bool   `json:"allow_comments"`
+	Title         string   `json:"title"`
+	Description   string   `json:"description"`
+	Address       string   `json:"address"`
+	ContactName   string   `json:"contact_name"`
+	MobileNumber  string   `json:"mobile_number"`
+	IncidentDate  Date     `json:"incident_date"`
+	IsAnonymous   *bool    `json:"is_anonymous"`
+	Visibility    string   `json:"visibility" binding:"omitempty,oneof=public private"`
+	AllowComments *bool    `json:"allow_comments"`
+	CategoryID    *uint    `json:"category_id"`
+	Tags          []string `json:"tags" binding:"omitempty,max=10,dive,max=50"`
+	Latitude      *float64 `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude     *float64 `json:"longitude" binding:"omitempty,min=-180,max=180"`
+}
+
+// PostDocument is the editable part of a post that PATCH /posts/:id applies
+// JSON merge patches (RFC 7386) to. The merged document must be a complete,
+// valid post: null removes a member, which is only allowed for category_id
+// and the coordinates.
+type PostDocument struct {
+	Title         string   `json:"title" binding:"required"`
+	Description   string   `json:"description" binding:"required"`
+	Address       string   `json:"address" binding:"required"`
+	ContactName   string   `json:"contact_name" binding:"required"`
+	MobileNumber  string   `json:"mobile_number" binding:"required"`
+	IncidentDate  *Date    `json:"incident_date" binding:"required"`
+	IsAnonymous   *bool    `json:"is_anonymous" binding:"required"`
+	Visibility    string   `json:"visibility" binding:"required,oneof=public private"`
+	AllowComments *bool    `json:"allow_comments" binding:"required"`
+	CategoryID    *uint    `json:"category_id,omitempty" binding:"omitempty,min=1"`
+	Tags          []string `json:"tags" binding:"max=10,dive,max=50"`
+	Latitude      *float64 `json:"latitude,omitempty" binding:"omitempty,min=-90,max=90"`
+	Longitude     *float64 `json:"longitude,omitempty" binding:"omitempty,min=-180,max=180"`
+}
+
+// PostFilter holds the query parameters accepted by GET /posts. Dates use
+// the YYYY-MM-DD format and both ends of a range are inclusive. Phone is
+// matched against the normalized mobile number through its blind index,
+// which the service puts in PhoneIndex.
+type PostFilter struct {
+	IncidentFrom *time.Time `form:"incident_from" time_format:"2006-01-02"`
+	IncidentTo   *time.Time `form:"incident_to" time_format:"2006-01-02"`
+	CreatedFrom  *time.Time `form:"created_from" time_format:"2006-01-02"`
+	CreatedTo    *time.Time `form:"created_to" time_format:"2006-01-02"`
+	Status       string     `form:"status" binding:"omitempty,alpha,max=20"`
+	AuthorID     *uint      `form:"author_id" binding:"omitempty,min=1"`
+	Visibility   string     `form:"visibility" binding:"omitempty,oneof=public private"`
+	IsAnonymous  *bool      `form:"is_anonymous"`
+	CategoryID   *uint      `form:"category_id" binding:"omitempty,min=1"`
+	Category     string     `form:"category" binding:"omitempty,max=50"`
+	Tag          string     `form:"tag" binding:"omitempty,max=50"`
+	Phone        string     `form:"phone" binding:"omitempty,max=30"`
+	PhoneIndex   string     `form:"-"`
+	Sort         string     `form:"sort" binding:"omitempty,oneof=created_at updated_at incident_date title"`
+	Order        string     `form:"order" binding:"omitempty,oneof=asc desc"`
+}
+
+// NearbyQuery holds the query parameters accepted by GET /posts/nearby.
+type NearbyQuery struct {
+	Latitude  *float64 `form:"lat" binding:"required,min=-90,max=90"`
+	Longitude *float64 `form:"lng" binding:"required,min=-180,max=180"`
+	RadiusKm  float64  `form:"radius_km" binding:"omitempty,gt=0,max=500"`
+}
+
+// MapQuery holds the corners of the map view accepted by GET /posts/map. A
+// min_lng above max_lng describes a view across the antimeridian.
+type MapQuery struct {
+	MinLat *float64 `form:"min_lat" binding:"required,min=-90,max=90"`
+	MinLng *float64 `form:"min_lng" binding:"required,min=-180,max=180"`
+	MaxLat *float64 `form:"max_lat" binding:"required,min=-90,max=90"`
+	MaxLng *float64 `form:"max_lng" binding:"required,min=-180,max=180"`
 }
 
 type CreateReportRequest struct {
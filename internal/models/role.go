@@ -8,6 +8,8 @@ type Role struct {
 	ID          uint         `json:"id" gorm:"primaryKey"`
 	Name        string       `json:"name" gorm:"unique;not null"`
 	Description string       `json:"description"`
+	ParentID    *uint        `json:"parent_id"` // permissions of the parent role are inherited
+	Parent      *Role        `json:"parent,omitempty" gorm:"foreignKey:ParentID"`
 	CreatedAt   time.Time    `json:"created_at"`
 	UpdatedAt   time.Time    `json:"updated_at"`
 	Users       []User       `json:"users,omitempty" gorm:"many2many:user_roles;"`
@@ -18,8 +20,8 @@ type Permission struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
 	Name        string    `json:"name" gorm:"unique;not null"`
 	Description string    `json:"description"`
-	Resource    string    `json:"resource" gorm:"not null"` // e.g., "posts", "reports"
-	Action      string    `json:"action" gorm:"not null"`   // e.g., "create", "read", "update", "delete"
+	Resource    string    `json:"resource" gorm:"not null"` // e.g., "posts", "reports" or "*" for all
+	Action      string    `json:"action" gorm:"not null"`   // e.g., "create", "read", "update", "delete" or "*" for all
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	Roles       []Role    `json:"roles,omitempty" gorm:"many2many:role_permissions;"`
@@ -38,3 +40,36 @@ type RolePermission struct {
 	PermissionID uint `gorm:"primaryKey"`
 	CreatedAt    time.Time
 }
+
+type CreateRoleRequest struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	Description string `json:"description"`
+	ParentID    *uint  `json:"parent_id"`
+}
+
+type SetRoleParentRequest struct {
+	ParentID *uint `json:"parent_id"`
+}
+
+type UpdateRoleRequest struct {
+	Name        string `json:"name" binding:"omitempty,max=50"`
+	Description string `json:"description"`
+}
+
+type CreatePermissionRequest struct {
+	Resource    string `json:"resource" binding:"required,max=50"`
+	Action      string `json:"action" binding:"required,max=50"`
+	Description string `json:"description"`
+}
+
+type UpdatePermissionRequest struct {
+	Description string `json:"description" binding:"required"`
+}
+
+type AssignRoleRequest struct {
+	RoleID uint `json:"role_id" binding:"required"`
+}
+
+type AssignPermissionRequest struct {
+	PermissionID uint `json:"permission_id" binding:"required"`
+}
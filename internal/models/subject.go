@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Subject is a person that posts are about, identified by an E.164 phone
+// number and a normalized name. Both are stored encrypted and matched by
+// their blind indexes.
+type Subject struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Phone      string    `json:"phone" gorm:"not null;serializer:encrypted"`
+	PhoneIndex string    `json:"-" gorm:"column:phone_bidx"`
+	Name       string    `json:"name" gorm:"not null;serializer:encrypted"`
+	NameKey    string    `json:"-" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SubjectProfile is a subject with the posts about them that the viewer can
+// see, and the number and date range of those incidents.
+type SubjectProfile struct {
+	Subject
+	IncidentCount int    `json:"incident_count"`
+	FirstIncident *Date  `json:"first_incident"`
+	LastIncident  *Date  `json:"last_incident"`
+	Posts         []Post `json:"posts"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type Category struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Slug        string    `json:"slug" gorm:"unique;not null"`
+	Name        string    `json:"name" gorm:"not null"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"unique;not null"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// TagUsage is a tag with the number of visible posts using it.
+type TagUsage struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+type CreateCategoryRequest struct {
+	Slug        string `json:"slug" binding:"required"`
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description"`
+}
+
+type UpdateCategoryRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// RefreshToken is a long-lived, single-use token that can be exchanged for a
+// new access token. Tokens issued from the same login share a FamilyID so the
+// whole chain can be revoked when reuse is detected.
+type RefreshToken struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash     string     `json:"-" gorm:"size:64;unique;not null"`
+	FamilyID      string     `json:"family_id" gorm:"size:64;not null;index"`
+	AccessTokenID string     `json:"access_token_id" gorm:"size:64;not null"`
+	MFA           bool       `json:"mfa" gorm:"column:mfa;default:false"`
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	ReplacedByID  *uint      `json:"replaced_by_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// RevokedToken records the jti of an access token that must no longer be
+// accepted, kept until the token would have expired anyway.
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"primaryKey;size:64"`
+	UserID    uint      `json:"user_id" gorm:"not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// PasswordResetToken is a single-use token emailed to a user who forgot
+// their password. Only the SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"size:64;unique;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" form:"token" binding:"required"`
+	Password string `json:"password" form:"password" binding:"required,min=6"`
+}
@@ -2,14 +2,17 @@ package models
 
 import (
 	"database/sql/driver"
+	"encoding/json"
 	"time"
 )
 
 type Date time.Time
 
 func (d *Date) UnmarshalJSON(b []byte) error {
-	s := string(b)
-	s = s[1 : len(s)-1] // Remove quotes
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
 	t, err := time.Parse("2006-01-02", s)
 	if err != nil {
 		return err
@@ -52,6 +55,13 @@ type User struct {
 	Roles     []Role    `json:"roles,omitempty" gorm:"many2many:user_roles;"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	VerifiedAt         *time.Time `json:"verified_at"`
+	VerificationSentAt *time.Time `json:"-"`
+
+	TOTPSecret    string     `json:"-" gorm:"column:totp_secret"`
+	TOTPEnabledAt *time.Time `json:"-" gorm:"column:totp_enabled_at"`
+	TOTPLastStep  int64      `json:"-" gorm:"column:totp_last_step"`
 }
 
 type LoginRequest struct {
@@ -66,3 +76,7 @@ type RegisterRequest struct {
 	Name     string `json:"name" binding:"required"`
 	Birthday Date   `json:"birthday" binding:"required"`
 }
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
@@ -0,0 +1,102 @@
+// Package pagination implements offset parameters and opaque keyset cursors
+// shared by the list endpoints.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidPage   = errors.New("page must be a positive integer")
+	ErrInvalidSize   = errors.New("page_size must be a positive integer")
+)
+
+// Cursor points at the last row of a page. Sort records the ordering the
+// cursor was created for, so it cannot be replayed against another one.
+type Cursor struct {
+	Sort     string `json:"s"`
+	Value    string `json:"v"`
+	ID       uint   `json:"id"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// Cursors are returned with a page; an empty string means there is no page
+// in that direction.
+type Cursors struct {
+	Next string `json:"next_cursor"`
+	Prev string `json:"prev_cursor"`
+}
+
+func Encode(c Cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses a cursor token; an empty token means the first page.
+func Decode(token string) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == 0 || c.Sort == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// ClampSize keeps a page size within 1..MaxPageSize.
+func ClampSize(size int) int {
+	if size < 1 {
+		return DefaultPageSize
+	}
+	if size > MaxPageSize {
+		return MaxPageSize
+	}
+	return size
+}
+
+// Page trims the limit+1 rows fetched for a keyset page, restores their
+// display order and builds the cursors around them. key returns the cursor
+// for a row.
+func Page[T any](rows []T, limit int, cursor *Cursor, key func(T) Cursor) ([]T, Cursors) {
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	backward := cursor != nil && cursor.Backward
+	if backward {
+		// Rows were fetched in reverse to walk backwards
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	var cursors Cursors
+	if len(rows) == 0 {
+		return rows, cursors
+	}
+
+	if hasMore || backward {
+		next := key(rows[len(rows)-1])
+		cursors.Next = Encode(next)
+	}
+	if (cursor != nil && !backward) || (backward && hasMore) {
+		prev := key(rows[0])
+		prev.Backward = true
+		cursors.Prev = Encode(prev)
+	}
+	return rows, cursors
+}
@@ -0,0 +1,116 @@
+// Package phone validates phone numbers and normalizes them to the E.164
+// format. Numbers without a country code are read in a configurable
+// default region.
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	ErrInvalidNumber = errors.New("invalid phone number")
+	ErrUnknownRegion = errors.New("unknown phone region")
+)
+
+const maxDigits = 15
+
+// Normalizer turns phone numbers into E.164 form, reading numbers written
+// without a country code in its default region.
+type Normalizer struct {
+	region string
+}
+
+// NewNormalizer returns a normalizer for the given ISO 3166 region code,
+// such as "US" or "GB". An empty region accepts international numbers
+// only.
+func NewNormalizer(region string) (*Normalizer, error) {
+	region = strings.ToUpper(strings.TrimSpace(region))
+	if region != "" {
+		if _, ok := regions[region]; !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownRegion, region)
+		}
+	}
+	return &Normalizer{region: region}, nil
+}
+
+// NewNormalizerFromEnv uses PHONE_DEFAULT_REGION as the default region.
+func NewNormalizerFromEnv() (*Normalizer, error) {
+	return NewNormalizer(os.Getenv("PHONE_DEFAULT_REGION"))
+}
+
+// Region is the default region, or "" when there is none.
+func (n *Normalizer) Region() string {
+	return n.region
+}
+
+// Normalize returns the E.164 form (+ and up to 15 digits) of a number.
+// Spaces, dashes, dots, slashes and parentheses are ignored. Numbers with
+// the wrong length for their country are rejected with an error wrapping
+// ErrInvalidNumber that says what is wrong.
+func (n *Normalizer) Normalize(raw string) (string, error) {
+	number := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')', '/', '\u00a0':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(raw))
+	if number == "" {
+		return "", invalid("number is empty")
+	}
+
+	international := strings.HasPrefix(number, "+")
+	number = strings.TrimPrefix(number, "+")
+	for _, r := range number {
+		if r < '0' || r > '9' {
+			return "", invalid("number may only contain digits, spaces, dashes, dots, parentheses and a leading +")
+		}
+	}
+
+	var home region
+	if n.region != "" {
+		home = regions[n.region]
+	}
+	if !international {
+		switch {
+		case strings.HasPrefix(number, "00"):
+			number, international = number[2:], true
+		case home.exitPrefix != "" && strings.HasPrefix(number, home.exitPrefix):
+			number, international = number[len(home.exitPrefix):], true
+		}
+	}
+
+	if international {
+		return parseInternational(number)
+	}
+	if n.region == "" {
+		return "", invalid("number must start with + and a country code")
+	}
+	return home.format(number)
+}
+
+// parseInternational validates digits that start with a country code.
+func parseInternational(number string) (string, error) {
+	if number == "" || number[0] == '0' {
+		return "", invalid("country code must not start with 0")
+	}
+
+	// Country codes are prefix-free, so at most one of these matches
+	for size := 1; size <= 3 && size < len(number); size++ {
+		if country, ok := countryCodes[number[:size]]; ok {
+			return country.format(number[size:])
+		}
+	}
+
+	if len(number) < 7 || len(number) > maxDigits {
+		return "", invalid("number must have 7 to 15 digits including the country code")
+	}
+	return "+" + number, nil
+}
+
+func invalid(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidNumber, reason)
+}
@@ -0,0 +1,117 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, tc := range []struct {
+		region string
+		raw    string
+		want   string
+	}{
+		// National numbers with and without the trunk prefix
+		{"GB", "020 7946 0018", "+442079460018"},
+		{"GB", "07700 900123", "+447700900123"},
+		{"GB", "7700 900123", "+447700900123"},
+		{"US", "(415) 555-2671", "+14155552671"},
+		{"US", "415.555.2671", "+14155552671"},
+		{"US", "1 415 555 2671", "+14155552671"},
+		{"RU", "8 (495) 123-45-67", "+74951234567"},
+
+		// International numbers, including a trunk prefix written in
+		// parentheses after the country code
+		{"GB", "+44 20 7946 0018", "+442079460018"},
+		{"GB", "+44 (0)20 7946 0018", "+442079460018"},
+		{"US", "+44 (0) 7700 900123", "+447700900123"},
+		{"GB", "+1 415 555 2671", "+14155552671"},
+		{"GB", "+7 495 123 45 67", "+74951234567"},
+		{"GB", "+999 123 4567", "+9991234567"},
+		{"", "+49 30 123456", "+4930123456"},
+		{"", " +33 1 23 45 67 89 ", "+33123456789"},
+
+		// Exit prefixes: 00 everywhere, 011 from North America and 810
+		// from Russia
+		{"GB", "0044 20 7946 0018", "+442079460018"},
+		{"", "0049 30 123456", "+4930123456"},
+		{"US", "011 44 20 7946 0018", "+442079460018"},
+		{"CA", "011 44 (0)20 7946 0018", "+442079460018"},
+		{"US", "00 44 20 7946 0018", "+442079460018"},
+		{"RU", "810 44 20 7946 0018", "+442079460018"},
+	} {
+		normalizer, err := NewNormalizer(tc.region)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := normalizer.Normalize(tc.raw)
+		if err != nil || got != tc.want {
+			t.Errorf("Normalize(%q) in %q = %q, %v; want %q", tc.raw, tc.region, got, err, tc.want)
+		}
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	for _, tc := range []struct {
+		region string
+		raw    string
+	}{
+		{"GB", ""},
+		{"GB", "   "},
+		{"GB", "+44 20 abcd 0018"},
+		{"GB", "020 7946 0018 ext 2"},
+		{"GB", "44+ 20 7946 0018"},
+		{"GB", "020 7946"},
+		{"GB", "+44 7700 900123 45"},
+		{"GB", "+0 20 7946 0018"},
+		{"GB", "+44"},
+		{"GB", "+123456"},
+		{"GB", "+999 1234 5678 9012 3"},
+
+		// An exit prefix only applies in the regions that use it
+		{"GB", "011 44 20 7946 0018"},
+
+		// Numbers without a country code need a default region
+		{"", "020 7946 0018"},
+
+		// North American area codes cannot start with 0 or 1, and a
+		// leading 1 is only a trunk prefix when the rest is long enough
+		{"US", "+1 015 555 2671"},
+		{"US", "+1 115 555 2671"},
+		{"US", "015 555 2671"},
+		{"CA", "1 115 555 2671"},
+		{"GB", "+1 (0)15 555 2671"},
+		{"US", "555-2671"},
+	} {
+		normalizer, err := NewNormalizer(tc.region)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := normalizer.Normalize(tc.raw); !errors.Is(err, ErrInvalidNumber) {
+			t.Errorf("Normalize(%q) in %q = %q, %v; want ErrInvalidNumber", tc.raw, tc.region, got, err)
+		}
+	}
+}
+
+func TestNewNormalizer(t *testing.T) {
+	for _, tc := range []struct {
+		region string
+		want   string
+		err    error
+	}{
+		{"GB", "GB", nil},
+		{" gb ", "GB", nil},
+		{"", "", nil},
+		{"XX", "", ErrUnknownRegion},
+		{"UK", "", ErrUnknownRegion},
+	} {
+		normalizer, err := NewNormalizer(tc.region)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("NewNormalizer(%q) error = %v, want %v", tc.region, err, tc.err)
+			continue
+		}
+		if err == nil && normalizer.Region() != tc.want {
+			t.Errorf("NewNormalizer(%q).Region() = %q, want %q", tc.region, normalizer.Region(), tc.want)
+		}
+	}
+}
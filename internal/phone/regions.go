@@ -0,0 +1,108 @@
+package phone
+
+import (
+	"fmt"
+	"strings"
+)
+
+// region holds the numbering rules of a country: its calling code, the
+// trunk prefix dialled before national numbers, the prefix for dialling
+// abroad when it is not 00, and the allowed length of the national
+// significant number (the digits after the country code).
+type region struct {
+	code       string
+	trunk      string
+	exitPrefix string
+	minLen     int
+	maxLen     int
+}
+
+// format validates a national number and prefixes the country code. A
+// trunk prefix is dropped when the number is too long with it, which also
+// covers international numbers written as +44 (0)20 ....
+func (r region) format(national string) (string, error) {
+	if r.trunk != "" && strings.HasPrefix(national, r.trunk) && len(national)-len(r.trunk) >= r.minLen {
+		national = national[len(r.trunk):]
+	}
+
+	if len(national) < r.minLen || len(national) > r.maxLen {
+		if r.minLen == r.maxLen {
+			return "", invalid(fmt.Sprintf("+%s numbers must have %d digits after the country code", r.code, r.minLen))
+		}
+		return "", invalid(fmt.Sprintf("+%s numbers must have %d to %d digits after the country code", r.code, r.minLen, r.maxLen))
+	}
+	if len(r.code)+len(national) > maxDigits {
+		return "", invalid("number must not have more than 15 digits")
+	}
+
+	// North American area codes never start with 0 or 1
+	if r.code == "1" && national[0] < '2' {
+		return "", invalid("+1 area codes must not start with 0 or 1")
+	}
+	return "+" + r.code + national, nil
+}
+
+// regions maps ISO 3166 region codes to their numbering rules. Lengths are
+// the range used by fixed and mobile numbers in each country.
+var regions = map[string]region{
+	"AE": {code: "971", trunk: "0", minLen: 8, maxLen: 9},
+	"AR": {code: "54", trunk: "0", minLen: 10, maxLen: 11},
+	"AT": {code: "43", trunk: "0", minLen: 4, maxLen: 13},
+	"AU": {code: "61", trunk: "0", minLen: 9, maxLen: 9},
+	"BD": {code: "880", trunk: "0", minLen: 8, maxLen: 10},
+	"BE": {code: "32", trunk: "0", minLen: 8, maxLen: 9},
+	"BR": {code: "55", trunk: "0", minLen: 10, maxLen: 11},
+	"CA": {code: "1", trunk: "1", exitPrefix: "011", minLen: 10, maxLen: 10},
+	"CH": {code: "41", trunk: "0", minLen: 9, maxLen: 9},
+	"CL": {code: "56", minLen: 9, maxLen: 9},
+	"CN": {code: "86", trunk: "0", minLen: 9, maxLen: 11},
+	"CO": {code: "57", minLen: 10, maxLen: 10},
+	"DE": {code: "49", trunk: "0", minLen: 6, maxLen: 13},
+	"DK": {code: "45", minLen: 8, maxLen: 8},
+	"EG": {code: "20", trunk: "0", minLen: 9, maxLen: 10},
+	"ES": {code: "34", minLen: 9, maxLen: 9},
+	"FI": {code: "358", trunk: "0", minLen: 5, maxLen: 12},
+	"FR": {code: "33", trunk: "0", minLen: 9, maxLen: 9},
+	"GB": {code: "44", trunk: "0", minLen: 9, maxLen: 10},
+	"GH": {code: "233", trunk: "0", minLen: 9, maxLen: 9},
+	"GR": {code: "30", minLen: 10, maxLen: 10},
+	"HK": {code: "852", minLen: 8, maxLen: 8},
+	"ID": {code: "62", trunk: "0", minLen: 8, maxLen: 12},
+	"IE": {code: "353", trunk: "0", minLen: 7, maxLen: 9},
+	"IL": {code: "972", trunk: "0", minLen: 8, maxLen: 9},
+	"IN": {code: "91", trunk: "0", minLen: 10, maxLen: 10},
+	"IT": {code: "39", minLen: 6, maxLen: 11},
+	"JP": {code: "81", trunk: "0", minLen: 9, maxLen: 10},
+	"KE": {code: "254", trunk: "0", minLen: 9, maxLen: 9},
+	"KR": {code: "82", trunk: "0", minLen: 8, maxLen: 10},
+	"MX": {code: "52", minLen: 10, maxLen: 10},
+	"MY": {code: "60", trunk: "0", minLen: 8, maxLen: 10},
+	"NG": {code: "234", trunk: "0", minLen: 8, maxLen: 10},
+	"NL": {code: "31", trunk: "0", minLen: 9, maxLen: 9},
+	"NO": {code: "47", minLen: 8, maxLen: 8},
+	"NZ": {code: "64", trunk: "0", minLen: 8, maxLen: 10},
+	"PH": {code: "63", trunk: "0", minLen: 8, maxLen: 10},
+	"PK": {code: "92", trunk: "0", minLen: 9, maxLen: 10},
+	"PL": {code: "48", minLen: 9, maxLen: 9},
+	"PT": {code: "351", minLen: 9, maxLen: 9},
+	"RU": {code: "7", trunk: "8", exitPrefix: "810", minLen: 10, maxLen: 10},
+	"SA": {code: "966", trunk: "0", minLen: 8, maxLen: 9},
+	"SE": {code: "46", trunk: "0", minLen: 7, maxLen: 9},
+	"SG": {code: "65", minLen: 8, maxLen: 8},
+	"TH": {code: "66", trunk: "0", minLen: 8, maxLen: 9},
+	"TR": {code: "90", trunk: "0", minLen: 10, maxLen: 10},
+	"UA": {code: "380", trunk: "0", minLen: 9, maxLen: 9},
+	"US": {code: "1", trunk: "1", exitPrefix: "011", minLen: 10, maxLen: 10},
+	"VN": {code: "84", trunk: "0", minLen: 9, maxLen: 10},
+	"ZA": {code: "27", trunk: "0", minLen: 9, maxLen: 9},
+}
+
+// countryCodes indexes the rules by calling code for international numbers.
+// Regions sharing a code (US and CA) share their rules.
+var countryCodes = func() map[string]region {
+	byCode := make(map[string]region, len(regions))
+	for _, r := range regions {
+		byCode[r.code] = r
+	}
+	return byCode
+}()
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"bad_boyes/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type AttachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAttachmentRepository(db *gorm.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+func (r *AttachmentRepository) CreateAttachment(attachment *models.Attachment) error {
+	return r.db.Create(attachment).Error
+}
+
+// GetAttachment returns an attachment only if it belongs to the given post.
+func (r *AttachmentRepository) GetAttachment(postID, id uint) (*models.Attachment, error) {
+	var attachment models.Attachment
+	err := r.db.Where("post_id = ?", postID).First(&attachment, id).Error
+	return &attachment, err
+}
+
+func (r *AttachmentRepository) ListAttachments(postID uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := r.db.Where("post_id = ?", postID).Order("created_at ASC, id ASC").Find(&attachments).Error
+	return attachments, err
+}
+
+// ListAttachmentsForPosts is used to remove stored files before the posts
+// are purged.
+func (r *AttachmentRepository) ListAttachmentsForPosts(postIDs []uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	if len(postIDs) == 0 {
+		return attachments, nil
+	}
+	err := r.db.Where("post_id IN ?", postIDs).Find(&attachments).Error
+	return attachments, err
+}
+
+func (r *AttachmentRepository) CountAttachments(postID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Attachment{}).Where("post_id = ?", postID).Count(&count).Error
+	return count, err
+}
+
+func (r *AttachmentRepository) DeleteAttachment(id uint) error {
+	result := r.db.Delete(&models.Attachment{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -17,7 +17,7 @@ func NewAuditRepository(db *gorm.DB) *AuditRepository {
 }
 
 func (r *AuditRepository) CreateLog(auditLog *models.AuditLog) error {
-	log.Printf("Creating audit log: %+v", auditLog)
+	log.Printf("Creating audit log: %s on %s %d", auditLog.Action, auditLog.TableName, auditLog.RecordID)
 
 	// Convert JSON fields to strings for storage
 	if auditLog.OldValues != nil {
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"bad_boyes/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CommentRepository struct {
+	db *gorm.DB
+}
+
+func NewCommentRepository(db *gorm.DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+func (r *CommentRepository) CreateComment(comment *models.Comment) error {
+	return r.db.Create(comment).Error
+}
+
+// GetComment returns a comment only if it belongs to the given post.
+func (r *CommentRepository) GetComment(postID, id uint) (*models.Comment, error) {
+	var comment models.Comment
+	err := r.db.Preload("User").Where("post_id = ?", postID).First(&comment, id).Error
+	return &comment, err
+}
+
+// ListThreads returns a page of top-level comments of a post together with
+// every reply below them, oldest first.
+func (r *CommentRepository) ListThreads(postID uint, page, pageSize int) ([]models.Comment, []models.Comment, int64, error) {
+	var roots []models.Comment
+	var total int64
+
+	query := r.db.Model(&models.Comment{}).Where("post_id = ? AND parent_id IS NULL", postID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, nil, 0, err
+	}
+
+	err := query.Preload("User").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Order("created_at ASC, id ASC").
+		Find(&roots).Error
+	if err != nil || len(roots) == 0 {
+		return roots, nil, total, err
+	}
+
+	rootIDs := make([]uint, len(roots))
+	for i, root := range roots {
+		rootIDs[i] = root.ID
+	}
+
+	var replies []models.Comment
+	err = r.db.Preload("User").
+		Where(`id IN (
+			WITH RECURSIVE thread (id) AS (
+				SELECT id FROM comments WHERE parent_id IN ?
+				UNION ALL
+				SELECT comments.id FROM comments JOIN thread ON comments.parent_id = thread.id
+			)
+			SELECT id FROM thread
+		)`, rootIDs).
+		Order("created_at ASC, id ASC").
+		Find(&replies).Error
+
+	return roots, replies, total, err
+}
+
+// UpdateCommentBody edits a visible comment and records when it was edited.
+func (r *CommentRepository) UpdateCommentBody(id uint, body string) error {
+	result := r.db.Model(&models.Comment{}).
+		Where("id = ? AND status = ?", id, models.CommentStatusVisible).
+		Updates(map[string]interface{}{"body": body, "edited_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SetCommentStatus hides a visible comment. The row is kept so that replies
+// below it stay in their thread.
+func (r *CommentRepository) SetCommentStatus(id uint, status string) error {
+	result := r.db.Model(&models.Comment{}).
+		Where("id = ? AND status = ?", id, models.CommentStatusVisible).
+		Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
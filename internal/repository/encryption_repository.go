@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+)
+
+// EncryptionRepository reads and writes encrypted columns as stored, without
+// the encrypted serializer, for re-encrypting rows in bulk.
+type EncryptionRepository struct {
+	db *gorm.DB
+}
+
+func NewEncryptionRepository(db *gorm.DB) *EncryptionRepository {
+	return &EncryptionRepository{db: db}
+}
+
+// ListRawRows returns the id and the stored values of columns for up to
+// limit rows of table with IDs above afterID, in ID order. Soft-deleted
+// rows are included.
+func (r *EncryptionRepository) ListRawRows(table string, columns []string, afterID uint, limit int) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	err := r.db.Table(table).
+		Select(append([]string{"id"}, columns...)).
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&rows).Error
+	return rows, err
+}
+
+// UpdateRawRow writes stored values to a row as they are.
+func (r *EncryptionRepository) UpdateRawRow(table string, id uint, values map[string]interface{}) error {
+	return r.db.Table(table).Where("id = ?", id).UpdateColumns(values).Error
+}
+
+// ReplacePostSearchTokens stores the search tokens of a post in place of the
+// ones it had.
+func (r *EncryptionRepository) ReplacePostSearchTokens(postID uint, tokens []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return replaceSearchTokenRows(tx, postID, tokens)
+	})
+}
+
+// FindSubjectByIndexes returns the ID of the subject with the given blind
+// indexes other than exceptID, or 0 if there is none.
+func (r *EncryptionRepository) FindSubjectByIndexes(phoneIndex, nameKey string, exceptID uint) (uint, error) {
+	var ids []uint
+	err := r.db.Table("subjects").
+		Where("phone_bidx = ? AND name_key = ? AND id <> ?", phoneIndex, nameKey, exceptID).
+		Limit(1).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrDuplicateEntry is returned when an insert or update violates a unique
+// constraint.
+var ErrDuplicateEntry = errors.New("duplicate entry")
+
+// ErrVersionConflict is returned when a record was changed by someone else
+// after it was read.
+var ErrVersionConflict = errors.New("record was changed concurrently")
+
+const mysqlErrDuplicateEntry = 1062
+
+// translateError maps driver specific errors to repository errors.
+func translateError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return ErrDuplicateEntry
+	}
+	return err
+}
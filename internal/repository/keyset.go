@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"bad_boyes/internal/pagination"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// keyset restricts a query to the rows after the cursor, ordered by column
+// and then by id as a tie-breaker, and fetches one extra row so the caller
+// can tell whether another page follows. Backward cursors walk the same
+// order in reverse.
+func keyset(query *gorm.DB, column, idColumn string, desc bool, cursor *pagination.Cursor, value interface{}, limit int) *gorm.DB {
+	descending := desc
+	if cursor != nil && cursor.Backward {
+		descending = !descending
+	}
+
+	op, direction := ">", "ASC"
+	if descending {
+		op, direction = "<", "DESC"
+	}
+
+	if cursor != nil {
+		condition := fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", column, op, column, idColumn, op)
+		query = query.Where(condition, value, value, cursor.ID)
+	}
+
+	return query.
+		Order(column + " " + direction).
+		Order(idColumn + " " + direction).
+		Limit(limit + 1)
+}
+
+// timeCursor reads back a timestamp stored in a cursor.
+func timeCursor(cursor *pagination.Cursor) (interface{}, error) {
+	if cursor == nil {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, cursor.Value)
+	if err != nil {
+		return nil, pagination.ErrInvalidCursor
+	}
+	return t, nil
+}
+
+func timeCursorKey(sort string, id uint, t time.Time) pagination.Cursor {
+	return pagination.Cursor{Sort: sort, Value: t.Format(time.RFC3339Nano), ID: id}
+}
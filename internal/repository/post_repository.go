@@ -1,9 +1,15 @@
 package repository
 
 import (
+	"bad_boyes/internal/geo"
 	"bad_boyes/internal/models"
+	"bad_boyes/internal/pagination"
+	"fmt"
+	"math"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PostRepository struct {
@@ -14,73 +20,571 @@ func NewPostRepository(db *gorm.DB) *PostRepository {
 	return &PostRepository{db: db}
 }
 
+// withPostRelations loads what is shown alongside a post.
+func withPostRelations(db *gorm.DB) *gorm.DB {
+	return db.Preload("User").Preload("Category").Preload("Tags")
+}
+
 func (r *PostRepository) CreatePost(post *models.Post) error {
-	return r.db.Create(post).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(post).Error; err != nil {
+			return err
+		}
+		return replaceSearchTokens(tx, post)
+	})
+}
+
+// replaceSearchTokens stores the search tokens of a post's encrypted fields
+// in place of the ones it had.
+func replaceSearchTokens(tx *gorm.DB, post *models.Post) error {
+	tokens, err := post.SearchTokens()
+	if err != nil {
+		return err
+	}
+	return replaceSearchTokenRows(tx, post.ID, tokens)
+}
+
+func replaceSearchTokenRows(tx *gorm.DB, postID uint, tokens []string) error {
+	if err := tx.Where("post_id = ?", postID).Delete(&models.PostSearchToken{}).Error; err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	rows := make([]models.PostSearchToken, len(tokens))
+	for i, token := range tokens {
+		rows[i] = models.PostSearchToken{PostID: postID, Token: token}
+	}
+	return tx.Create(&rows).Error
 }
 
 func (r *PostRepository) GetPostByID(id uint) (*models.Post, error) {
 	var post models.Post
-	err := r.db.Preload("User").First(&post, id).Error
+	err := r.db.Scopes(withPostRelations).First(&post, id).Error
 	return &post, err
 }
 
-func (r *PostRepository) UpdatePost(post *models.Post) error {
-	// Create backup in post_history
-	history := &models.PostHistory{
-		PostID:        post.ID,
-		UserID:        post.UserID,
-		Title:         post.Title,
-		Description:   post.Description,
-		Address:       post.Address,
-		ContactName:   post.ContactName,
-		MobileNumber:  post.MobileNumber,
-		IncidentDate:  post.IncidentDate,
-		Status:        post.Status,
-		IsAnonymous:   post.IsAnonymous,
-		Visibility:    post.Visibility,
-		AllowComments: post.AllowComments,
-	}
-	if err := r.db.Create(history).Error; err != nil {
-		return err
-	}
+// UpdatePost saves a post after recording the stored revision it replaces
+// in post_history, along with the editor and the fields the update changed.
+// The post is only written if its version is still the one it was read
+// with, otherwise ErrVersionConflict is returned; on success the version is
+// incremented. With replaceTags the post's tags are set to post.Tags in the
+// same transaction.
+func (r *PostRepository) UpdatePost(post *models.Post, editorID uint, changedFields []string, replaceTags bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var previous models.Post
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Tags").First(&previous, post.ID).Error
+		if err != nil {
+			return err
+		}
+		if previous.Version != post.Version {
+			return ErrVersionConflict
+		}
+
+		// Create backup in post_history
+		history := previous.Snapshot()
+		history.EditorID = &editorID
+		history.ChangedFields = changedFields
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+
+		version := post.Version
+		post.Version++
+		result := tx.Model(post).Omit(clause.Associations).Select("*").
+			Where("version = ?", version).
+			Updates(post)
+		if result.Error == nil && result.RowsAffected == 0 {
+			result.Error = ErrVersionConflict
+		}
+		if result.Error != nil {
+			post.Version = version
+			return result.Error
+		}
+		if replaceTags {
+			if err := tx.Model(post).Association("Tags").Replace(post.Tags); err != nil {
+				post.Version = version
+				return err
+			}
+		}
+		return replaceSearchTokens(tx, post)
+	})
+}
 
-	return r.db.Save(post).Error
+// DeletePost moves a post to the trash if it is still at the given version,
+// and returns ErrVersionConflict otherwise. Its history and reports are kept
+// until the post is purged.
+func (r *PostRepository) DeletePost(id, version uint) error {
+	result := r.db.Where("version = ?", version).Delete(&models.Post{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
 }
 
-func (r *PostRepository) DeletePost(id uint) error {
-	return r.db.Delete(&models.Post{}, id).Error
+// GetDeletedPostByID returns a post that is in the trash.
+func (r *PostRepository) GetDeletedPostByID(id uint) (*models.Post, error) {
+	var post models.Post
+	err := r.db.Unscoped().Scopes(withPostRelations).Where("deleted_at IS NOT NULL").First(&post, id).Error
+	return &post, err
 }
 
-func (r *PostRepository) ListPosts(page, pageSize int, userID *uint) ([]models.Post, int64, error) {
+// ListDeletedPosts returns posts in the trash, most recently deleted first.
+// A nil userID lists the trash of every user.
+func (r *PostRepository) ListDeletedPosts(userID *uint, page, pageSize int) ([]models.Post, int64, error) {
 	var posts []models.Post
 	var total int64
 
-	query := r.db.Model(&models.Post{}).Where("visibility = ?", "public")
+	query := r.db.Unscoped().Model(&models.Post{}).Where("posts.deleted_at IS NOT NULL")
 	if userID != nil {
-		query = query.Or("user_id = ?", userID)
+		query = query.Where("posts.user_id = ?", *userID)
 	}
 
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Scopes(withPostRelations).
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Order("posts.deleted_at DESC, posts.id DESC").
+		Find(&posts).Error
+
+	return posts, total, err
+}
+
+func (r *PostRepository) RestorePost(id uint) error {
+	result := r.db.Unscoped().Model(&models.Post{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListPurgeablePostIDs returns up to limit posts deleted before the cutoff.
+func (r *PostRepository) ListPurgeablePostIDs(before time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.Unscoped().Model(&models.Post{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Order("deleted_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// PurgePosts permanently removes posts in the trash. Their history,
+// comments, tags, search tokens and attachment rows go with them through
+// ON DELETE CASCADE; reports have no foreign key to posts and are deleted
+// in the same transaction.
+func (r *PostRepository) PurgePosts(ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var purged int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		trashed := tx.Unscoped().Model(&models.Post{}).
+			Select("id").
+			Where("id IN ? AND deleted_at IS NOT NULL", ids)
+		if err := tx.Where("post_id IN (?)", trashed).Delete(&models.Report{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().
+			Where("id IN ? AND deleted_at IS NOT NULL", ids).
+			Delete(&models.Post{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
+
+// postSortColumns whitelists the columns ListPosts can be ordered by.
+var postSortColumns = map[string]string{
+	"created_at":    "posts.created_at",
+	"updated_at":    "posts.updated_at",
+	"incident_date": "posts.incident_date",
+	"title":         "posts.title",
+}
+
+// ListPosts returns public posts plus the user's own posts matching the
+// filter. When includePrivate is set every post is considered.
+func (r *PostRepository) ListPosts(filter models.PostFilter, page, pageSize int, userID *uint, includePrivate bool) ([]models.Post, int64, error) {
+	var posts []models.Post
+	var total int64
+
+	query := r.visiblePosts(r.db.Model(&models.Post{}), userID, includePrivate)
+	query = applyPostFilter(query, filter)
+
 	err := query.Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
-	err = query.Preload("User").
+	column, ok := postSortColumns[filter.Sort]
+	if !ok {
+		column = "posts.created_at"
+	}
+	direction := "DESC"
+	if filter.Order == "asc" {
+		direction = "ASC"
+	}
+
+	err = query.Scopes(withPostRelations).
 		Offset((page - 1) * pageSize).
 		Limit(pageSize).
-		Order("created_at DESC").
+		Order(column + " " + direction).
+		Order("posts.id " + direction).
 		Find(&posts).Error
 
 	return posts, total, err
 }
 
+// ListPostsByCursor is the keyset counterpart of ListPosts. It does not
+// count matching rows, which is what keeps deep pages cheap.
+func (r *PostRepository) ListPostsByCursor(filter models.PostFilter, cursor *pagination.Cursor, pageSize int, userID *uint, includePrivate bool) ([]models.Post, pagination.Cursors, error) {
+	sort := filter.Sort
+	column, ok := postSortColumns[sort]
+	if !ok {
+		sort, column = "created_at", "posts.created_at"
+	}
+	desc := filter.Order != "asc"
+	sortKey := sort + ":" + filter.Order
+
+	var value interface{}
+	if cursor != nil {
+		if cursor.Sort != sortKey {
+			return nil, pagination.Cursors{}, pagination.ErrInvalidCursor
+		}
+		value = cursor.Value
+		if sort == "created_at" || sort == "updated_at" {
+			t, err := timeCursor(cursor)
+			if err != nil {
+				return nil, pagination.Cursors{}, err
+			}
+			value = t
+		}
+	}
+
+	query := r.visiblePosts(r.db.Model(&models.Post{}), userID, includePrivate)
+	query = applyPostFilter(query, filter)
+
+	var posts []models.Post
+	err := keyset(query, column, "posts.id", desc, cursor, value, pageSize).
+		Scopes(withPostRelations).
+		Find(&posts).Error
+	if err != nil {
+		return nil, pagination.Cursors{}, err
+	}
+
+	posts, cursors := pagination.Page(posts, pageSize, cursor, func(post models.Post) pagination.Cursor {
+		key := pagination.Cursor{Sort: sortKey, ID: post.ID}
+		switch sort {
+		case "updated_at":
+			key.Value = post.UpdatedAt.Format(time.RFC3339Nano)
+		case "incident_date":
+			key.Value = time.Time(post.IncidentDate).Format("2006-01-02")
+		case "title":
+			key.Value = post.Title
+		default:
+			key.Value = post.CreatedAt.Format(time.RFC3339Nano)
+		}
+		return key
+	})
+	return posts, cursors, nil
+}
+
+func applyPostFilter(query *gorm.DB, filter models.PostFilter) *gorm.DB {
+	if filter.IncidentFrom != nil {
+		query = query.Where("posts.incident_date >= ?", filter.IncidentFrom.Format("2006-01-02"))
+	}
+	if filter.IncidentTo != nil {
+		query = query.Where("posts.incident_date <= ?", filter.IncidentTo.Format("2006-01-02"))
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("posts.created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		// created_to covers the whole day
+		query = query.Where("posts.created_at < ?", filter.CreatedTo.AddDate(0, 0, 1))
+	}
+	if filter.Status != "" {
+		query = query.Where("posts.status = ?", filter.Status)
+	}
+	if filter.AuthorID != nil {
+		query = query.Where("posts.user_id = ?", *filter.AuthorID)
+	}
+	if filter.Visibility != "" {
+		query = query.Where("posts.visibility = ?", filter.Visibility)
+	}
+	if filter.IsAnonymous != nil {
+		query = query.Where("posts.is_anonymous = ?", *filter.IsAnonymous)
+	}
+	if filter.CategoryID != nil {
+		query = query.Where("posts.category_id = ?", *filter.CategoryID)
+	}
+	if filter.Category != "" {
+		query = query.Where("posts.category_id IN (SELECT id FROM categories WHERE slug = ?)", filter.Category)
+	}
+	if filter.Tag != "" {
+		query = query.Where(`EXISTS (
+			SELECT 1 FROM post_tags JOIN tags ON tags.id = post_tags.tag_id
+			WHERE post_tags.post_id = posts.id AND tags.name = ?
+		)`, filter.Tag)
+	}
+	if filter.PhoneIndex != "" {
+		query = query.Where("posts.mobile_number_e164_bidx = ?", filter.PhoneIndex)
+	}
+	return query
+}
+
+// visiblePosts restricts a posts query to public posts and the user's own
+// posts, unless includePrivate is set.
+func (r *PostRepository) visiblePosts(query *gorm.DB, userID *uint, includePrivate bool) *gorm.DB {
+	if includePrivate {
+		return query
+	}
+	if userID != nil {
+		return query.Where(r.db.Where("posts.visibility = ?", "public").Or("posts.user_id = ?", *userID))
+	}
+	return query.Where("posts.visibility = ?", "public")
+}
+
+// ListSubjectPosts returns the posts about a subject, latest incident
+// first, with the same visibility rules as ListPosts.
+func (r *PostRepository) ListSubjectPosts(subjectID uint, userID *uint, includePrivate bool) ([]models.Post, error) {
+	var posts []models.Post
+	query := r.visiblePosts(r.db.Where("posts.subject_id = ?", subjectID), userID, includePrivate)
+	err := query.Scopes(withPostRelations).
+		Order("posts.incident_date DESC, posts.id DESC").
+		Find(&posts).Error
+	return posts, err
+}
+
+// ListPostPhones returns the phone fields of up to limit posts with IDs
+// above afterID, trashed ones included, in ID order.
+func (r *PostRepository) ListPostPhones(afterID uint, limit int) ([]models.Post, error) {
+	var posts []models.Post
+	err := r.db.Unscoped().
+		Select("id, contact_name, mobile_number, mobile_number_e164, subject_id").
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&posts).Error
+	return posts, err
+}
+
+// UpdatePostPhone writes the normalized number and subject of a post
+// without touching updated_at or its history.
+func (r *PostRepository) UpdatePostPhone(post *models.Post) error {
+	if err := post.IndexMobileNumber(); err != nil {
+		return err
+	}
+	return r.db.Unscoped().Model(post).
+		Select("mobile_number_e164", "mobile_number_e164_bidx", "subject_id").
+		UpdateColumns(post).Error
+}
+
+// Address and contact name are encrypted, so only title and description
+// are in the full-text index; the words of the other two are matched through
+// post_search_tokens, each matching word adding one to the relevance.
+const (
+	postSearchMatch  = "MATCH (posts.title, posts.description) AGAINST (? IN NATURAL LANGUAGE MODE)"
+	postTokenMatches = "(SELECT COUNT(*) FROM post_search_tokens WHERE post_search_tokens.post_id = posts.id AND post_search_tokens.token IN ?)"
+)
+
+// SearchPosts runs a full-text search over posts, most relevant first, with
+// the same visibility rules as ListPosts. tokens are the blind indexes of
+// the query's words.
+func (r *PostRepository) SearchPosts(q string, tokens []string, page, pageSize int, userID *uint, includePrivate bool) ([]models.PostSearchResult, int64, error) {
+	var total int64
+
+	// IN () is invalid SQL, and no token matches an empty string
+	if len(tokens) == 0 {
+		tokens = []string{""}
+	}
+	matches := r.db.Where(postSearchMatch, q).Or("posts.id IN (SELECT post_id FROM post_search_tokens WHERE token IN ?)", tokens)
+	query := r.visiblePosts(r.db.Model(&models.Post{}).Where(matches), userID, includePrivate)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var hits []struct {
+		ID        uint
+		Relevance float64
+	}
+	err := query.Select("posts.id, "+postSearchMatch+" + "+postTokenMatches+" AS relevance", q, tokens).
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Order("relevance DESC, posts.created_at DESC").
+		Scan(&hits).Error
+	if err != nil || len(hits) == 0 {
+		return nil, total, err
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+
+	byID, err := r.postsByID(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Keep the relevance order of the first query
+	results := make([]models.PostSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		if post, ok := byID[hit.ID]; ok {
+			results = append(results, models.PostSearchResult{Post: post, Relevance: hit.Relevance})
+		}
+	}
+	return results, total, nil
+}
+
+// ExactLocation makes location queries filter and sort on the exact
+// coordinates of posts.
+const ExactLocation = -1
+
+// postLocation is the point location queries work with: posts.location, or
+// with decimals of zero or more, the post's coordinates rounded to that many
+// decimals, so callers who are only shown rounded coordinates cannot narrow
+// down the exact ones with small or shifted search areas.
+func postLocation(decimals int) string {
+	if decimals < 0 {
+		return "posts.location"
+	}
+	return fmt.Sprintf("POINT(ROUND(posts.longitude, %[1]d), ROUND(posts.latitude, %[1]d))", decimals)
+}
+
+func postDistance(decimals int) string {
+	return "ST_Distance_Sphere(" + postLocation(decimals) + ", ST_GeomFromText(?, 0))"
+}
+
+// withinBoxes restricts a posts query to located posts inside any of the
+// boxes, using the spatial index on posts.location. Rounded locations can
+// lie up to half a step away from the stored one, so the index is searched
+// with boxes grown by that much.
+func (r *PostRepository) withinBoxes(query *gorm.DB, boxes []geo.Box, decimals int) *gorm.DB {
+	inside := r.db
+	for i, box := range boxes {
+		condition := r.db.Where("MBRContains(ST_GeomFromText(?, 0), posts.location)", box.WKT())
+		if decimals >= 0 {
+			margin := 0.5 / math.Pow(10, float64(decimals))
+			grown := geo.Box{MinLat: box.MinLat - margin, MinLng: box.MinLng - margin, MaxLat: box.MaxLat + margin, MaxLng: box.MaxLng + margin}
+			condition = r.db.Where("MBRContains(ST_GeomFromText(?, 0), posts.location)", grown.WKT()).
+				Where("MBRContains(ST_GeomFromText(?, 0), "+postLocation(decimals)+")", box.WKT())
+		}
+		if i == 0 {
+			inside = inside.Where(condition)
+		} else {
+			inside = inside.Or(condition)
+		}
+	}
+	return query.Where("posts.latitude IS NOT NULL").Where(inside)
+}
+
+// NearbyPosts returns located posts within radiusKm of center, closest
+// first, with the same visibility rules as ListPosts. Posts are matched on
+// their coordinates rounded to decimals, or on their exact location with
+// ExactLocation.
+func (r *PostRepository) NearbyPosts(center geo.Coordinates, radiusKm float64, decimals, page, pageSize int, userID *uint, includePrivate bool) ([]models.PostNearbyResult, int64, error) {
+	var total int64
+
+	point := fmt.Sprintf("POINT(%f %f)", center.Longitude, center.Latitude)
+	distance := postDistance(decimals)
+	query := r.db.Model(&models.Post{})
+	query = r.withinBoxes(query, geo.BoxAround(center, radiusKm), decimals).
+		Where(distance+" <= ?", point, radiusKm*1000)
+	query = r.visiblePosts(query, userID, includePrivate)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var hits []struct {
+		ID       uint
+		Distance float64
+	}
+	err := query.Select("posts.id, "+distance+" AS distance", point).
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Order("distance, posts.id").
+		Scan(&hits).Error
+	if err != nil || len(hits) == 0 {
+		return nil, total, err
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	byID, err := r.postsByID(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Keep the distance order of the first query
+	results := make([]models.PostNearbyResult, 0, len(hits))
+	for _, hit := range hits {
+		if post, ok := byID[hit.ID]; ok {
+			results = append(results, models.PostNearbyResult{Post: post, DistanceKm: hit.Distance / 1000})
+		}
+	}
+	return results, total, nil
+}
+
+// PostsInBoxes returns up to limit located posts inside the boxes of a map
+// view, newest first, with the same visibility rules as ListPosts. Posts
+// are matched on their coordinates rounded to decimals, or on their exact
+// location with ExactLocation.
+func (r *PostRepository) PostsInBoxes(boxes []geo.Box, decimals, limit int, userID *uint, includePrivate bool) ([]models.Post, int64, error) {
+	var posts []models.Post
+	var total int64
+
+	query := r.visiblePosts(r.withinBoxes(r.db.Model(&models.Post{}), boxes, decimals), userID, includePrivate)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Scopes(withPostRelations).
+		Order("posts.created_at DESC, posts.id DESC").
+		Limit(limit).
+		Find(&posts).Error
+	return posts, total, err
+}
+
+func (r *PostRepository) postsByID(ids []uint) (map[uint]models.Post, error) {
+	var posts []models.Post
+	if err := r.db.Scopes(withPostRelations).Where("id IN ?", ids).Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+	return byID, nil
+}
+
+// withTrashed lets reports still show a post that is in the trash.
+func withTrashed(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
 func (r *PostRepository) CreateReport(report *models.Report) error {
 	return r.db.Create(report).Error
 }
 
 func (r *PostRepository) GetReportByID(id uint) (*models.Report, error) {
 	var report models.Report
-	err := r.db.Preload("Post").Preload("Reporter").First(&report, id).Error
+	err := r.db.Preload("Post", withTrashed).Preload("Reporter").First(&report, id).Error
 	return &report, err
 }
 
@@ -97,7 +601,7 @@ func (r *PostRepository) ListReports(page, pageSize int) ([]models.Report, int64
 		return nil, 0, err
 	}
 
-	err = r.db.Preload("Post").Preload("Reporter").
+	err = r.db.Preload("Post", withTrashed).Preload("Reporter").
 		Offset((page - 1) * pageSize).
 		Limit(pageSize).
 		Order("created_at DESC").
@@ -106,6 +610,63 @@ func (r *PostRepository) ListReports(page, pageSize int) ([]models.Report, int64
 	return reports, total, err
 }
 
+// ListReportsByCursor pages through reports, newest first.
+func (r *PostRepository) ListReportsByCursor(cursor *pagination.Cursor, pageSize int) ([]models.Report, pagination.Cursors, error) {
+	const sortKey = "reports:created_at"
+	if cursor != nil && cursor.Sort != sortKey {
+		return nil, pagination.Cursors{}, pagination.ErrInvalidCursor
+	}
+	value, err := timeCursor(cursor)
+	if err != nil {
+		return nil, pagination.Cursors{}, err
+	}
+
+	var reports []models.Report
+	err = keyset(r.db.Model(&models.Report{}), "reports.created_at", "reports.id", true, cursor, value, pageSize).
+		Preload("Post", withTrashed).Preload("Reporter").
+		Find(&reports).Error
+	if err != nil {
+		return nil, pagination.Cursors{}, err
+	}
+
+	reports, cursors := pagination.Page(reports, pageSize, cursor, func(report models.Report) pagination.Cursor {
+		return timeCursorKey(sortKey, report.ID, report.CreatedAt)
+	})
+	return reports, cursors, nil
+}
+
+// GetPostHistoryByCursor pages through the history of a post, newest first.
+func (r *PostRepository) GetPostHistoryByCursor(postID uint, cursor *pagination.Cursor, pageSize int) ([]models.PostHistory, pagination.Cursors, error) {
+	const sortKey = "post_history:created_at"
+	if cursor != nil && cursor.Sort != sortKey {
+		return nil, pagination.Cursors{}, pagination.ErrInvalidCursor
+	}
+	value, err := timeCursor(cursor)
+	if err != nil {
+		return nil, pagination.Cursors{}, err
+	}
+
+	var history []models.PostHistory
+	query := r.db.Model(&models.PostHistory{}).Where("post_id = ?", postID)
+	err = keyset(query, "post_history.created_at", "post_history.id", true, cursor, value, pageSize).
+		Find(&history).Error
+	if err != nil {
+		return nil, pagination.Cursors{}, err
+	}
+
+	history, cursors := pagination.Page(history, pageSize, cursor, func(entry models.PostHistory) pagination.Cursor {
+		return timeCursorKey(sortKey, entry.ID, entry.CreatedAt)
+	})
+	return history, cursors, nil
+}
+
+// GetPostRevision returns the history entry holding a revision of a post.
+func (r *PostRepository) GetPostRevision(postID, revision uint) (*models.PostHistory, error) {
+	var history models.PostHistory
+	err := r.db.Where("post_id = ? AND revision = ?", postID, revision).First(&history).Error
+	return &history, err
+}
+
 func (r *PostRepository) GetPostHistory(postID uint) ([]models.PostHistory, error) {
 	var history []models.PostHistory
 	err := r.db.Where("post_id = ?", postID).Order("created_at DESC").Find(&history).Error
@@ -6,6 +6,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// maxRoleDepth bounds how many levels of role inheritance are followed.
+const maxRoleDepth = 32
+
 type RoleRepository struct {
 	db *gorm.DB
 }
@@ -16,44 +19,135 @@ func NewRoleRepository(db *gorm.DB) *RoleRepository {
 
 func (r *RoleRepository) CreateRole(role *models.Role) (*models.Role, error) {
 	err := r.db.Create(role).Error
-	return role, err
+	return role, translateError(err)
+}
+
+func (r *RoleRepository) GetRoleByID(id uint) (*models.Role, error) {
+	var role models.Role
+	err := r.db.Preload("Permissions").Preload("Parent").First(&role, id).Error
+	return &role, err
+}
+
+func (r *RoleRepository) ListRoles() ([]models.Role, error) {
+	var roles []models.Role
+	err := r.db.Preload("Permissions").Preload("Parent").Order("name").Find(&roles).Error
+	return roles, err
+}
+
+func (r *RoleRepository) UpdateRole(role *models.Role) error {
+	return translateError(r.db.Model(role).Select("name", "description").Updates(role).Error)
+}
+
+func (r *RoleRepository) DeleteRole(id uint) error {
+	return r.db.Delete(&models.Role{}, id).Error
 }
 
 func (r *RoleRepository) AssignRoleToUser(userID, roleID uint) error {
-	return r.db.Create(&models.UserRole{
+	return translateError(r.db.Create(&models.UserRole{
 		UserID: userID,
 		RoleID: roleID,
-	}).Error
+	}).Error)
 }
 
+// RemoveRoleFromUser removes the assignment, returning gorm.ErrRecordNotFound
+// when the user did not hold the role.
 func (r *RoleRepository) RemoveRoleFromUser(userID, roleID uint) error {
-	return r.db.Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&models.UserRole{}).Error
+	result := r.db.Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&models.UserRole{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
 func (r *RoleRepository) GetUserRoles(userID uint) ([]models.Role, error) {
 	var roles []models.Role
-	err := r.db.Model(&models.User{}).Where("id = ?", userID).Association("Roles").Find(&roles)
+	err := r.db.Model(&models.User{ID: userID}).Association("Roles").Find(&roles)
 	return roles, err
 }
 
+func (r *RoleRepository) UserExists(userID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("id = ?", userID).Count(&count).Error
+	return count > 0, err
+}
+
 func (r *RoleRepository) CreatePermission(permission *models.Permission) (*models.Permission, error) {
 	err := r.db.Create(permission).Error
-	return permission, err
+	return permission, translateError(err)
+}
+
+func (r *RoleRepository) GetPermissionByID(id uint) (*models.Permission, error) {
+	var permission models.Permission
+	err := r.db.First(&permission, id).Error
+	return &permission, err
+}
+
+func (r *RoleRepository) ListPermissions() ([]models.Permission, error) {
+	var permissions []models.Permission
+	err := r.db.Order("resource, action").Find(&permissions).Error
+	return permissions, err
+}
+
+func (r *RoleRepository) UpdatePermission(permission *models.Permission) error {
+	return translateError(r.db.Model(permission).Select("description").Updates(permission).Error)
+}
+
+func (r *RoleRepository) DeletePermission(id uint) error {
+	return r.db.Delete(&models.Permission{}, id).Error
 }
 
 func (r *RoleRepository) AssignPermissionToRole(roleID, permissionID uint) error {
-	return r.db.Create(&models.RolePermission{
+	return translateError(r.db.Create(&models.RolePermission{
 		RoleID:       roleID,
 		PermissionID: permissionID,
-	}).Error
+	}).Error)
 }
 
+// RemovePermissionFromRole removes the grant, returning
+// gorm.ErrRecordNotFound when the role did not have the permission.
 func (r *RoleRepository) RemovePermissionFromRole(roleID, permissionID uint) error {
-	return r.db.Where("role_id = ? AND permission_id = ?", roleID, permissionID).Delete(&models.RolePermission{}).Error
+	result := r.db.Where("role_id = ? AND permission_id = ?", roleID, permissionID).Delete(&models.RolePermission{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
 func (r *RoleRepository) GetRolePermissions(roleID uint) ([]models.Permission, error) {
 	var permissions []models.Permission
-	err := r.db.Model(&models.Role{}).Where("id = ?", roleID).Association("Permissions").Find(&permissions)
+	err := r.db.Model(&models.Role{ID: roleID}).Association("Permissions").Find(&permissions)
 	return permissions, err
 }
+
+// GetUserPermissions returns the distinct permissions granted to the user
+// through any of their roles or the ancestors of those roles, in a single
+// query. The depth limit guards against cycles that slipped into the data.
+func (r *RoleRepository) GetUserPermissions(userID uint) ([]models.Permission, error) {
+	var permissions []models.Permission
+	err := r.db.Raw(`
+		WITH RECURSIVE role_tree (id, depth) AS (
+			SELECT role_id, 0 FROM user_roles WHERE user_id = ?
+			UNION ALL
+			SELECT roles.parent_id, role_tree.depth + 1
+			FROM roles JOIN role_tree ON roles.id = role_tree.id
+			WHERE roles.parent_id IS NOT NULL AND role_tree.depth < ?
+		)
+		SELECT DISTINCT permissions.*
+		FROM permissions
+		JOIN role_permissions ON role_permissions.permission_id = permissions.id
+		JOIN role_tree ON role_tree.id = role_permissions.role_id`,
+		userID, maxRoleDepth).
+		Scan(&permissions).Error
+	return permissions, err
+}
+
+// SetRoleParent changes the role a role inherits from; nil removes it.
+func (r *RoleRepository) SetRoleParent(roleID uint, parentID *uint) error {
+	return r.db.Model(&models.Role{}).Where("id = ?", roleID).Update("parent_id", parentID).Error
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"bad_boyes/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SubjectRepository struct {
+	db *gorm.DB
+}
+
+func NewSubjectRepository(db *gorm.DB) *SubjectRepository {
+	return &SubjectRepository{db: db}
+}
+
+func (r *SubjectRepository) GetSubject(id uint) (*models.Subject, error) {
+	var subject models.Subject
+	err := r.db.First(&subject, id).Error
+	return &subject, err
+}
+
+// FindOrCreateSubject returns the subject with the same phone and name
+// blind indexes, creating it from the given one if there is none yet.
+func (r *SubjectRepository) FindOrCreateSubject(subject *models.Subject) (*models.Subject, error) {
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(subject).Error; err != nil {
+		return nil, err
+	}
+
+	// The ID of an existing subject is not returned by the insert
+	var found models.Subject
+	err := r.db.Where("phone_bidx = ? AND name_key = ?", subject.PhoneIndex, subject.NameKey).First(&found).Error
+	return &found, err
+}
+
+// MergeSubject moves the posts of a duplicate subject to another one and
+// deletes the duplicate.
+func (r *SubjectRepository) MergeSubject(duplicateID, keepID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Post{}).Unscoped().
+			Where("subject_id = ?", duplicateID).
+			UpdateColumn("subject_id", keepID).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&models.Subject{}, duplicateID).Error
+	})
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"bad_boyes/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TaxonomyRepository struct {
+	db *gorm.DB
+}
+
+func NewTaxonomyRepository(db *gorm.DB) *TaxonomyRepository {
+	return &TaxonomyRepository{db: db}
+}
+
+func (r *TaxonomyRepository) ListCategories() ([]models.Category, error) {
+	var categories []models.Category
+	err := r.db.Order("name ASC").Find(&categories).Error
+	return categories, err
+}
+
+func (r *TaxonomyRepository) GetCategory(id uint) (*models.Category, error) {
+	var category models.Category
+	err := r.db.First(&category, id).Error
+	return &category, err
+}
+
+func (r *TaxonomyRepository) CreateCategory(category *models.Category) error {
+	return translateError(r.db.Create(category).Error)
+}
+
+func (r *TaxonomyRepository) UpdateCategory(category *models.Category) error {
+	return r.db.Save(category).Error
+}
+
+// DeleteCategory removes a category; posts using it become uncategorised.
+func (r *TaxonomyRepository) DeleteCategory(id uint) error {
+	result := r.db.Delete(&models.Category{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindOrCreateTags returns the tags with the given names, creating the ones
+// that do not exist yet.
+func (r *TaxonomyRepository) FindOrCreateTags(names []string) ([]models.Tag, error) {
+	if len(names) == 0 {
+		return []models.Tag{}, nil
+	}
+
+	tags := make([]models.Tag, len(names))
+	for i, name := range names {
+		tags[i] = models.Tag{Name: name}
+	}
+	err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// IDs of tags that already existed are not returned by the insert
+	var found []models.Tag
+	err = r.db.Where("name IN ?", names).Find(&found).Error
+	return found, err
+}
+
+// PopularTags returns the most used tags on public posts created since the
+// given time.
+func (r *TaxonomyRepository) PopularTags(since time.Time, limit int) ([]models.TagUsage, error) {
+	var usage []models.TagUsage
+	err := r.db.Table("tags").
+		Select("tags.id, tags.name, COUNT(*) AS count").
+		Joins("JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("JOIN posts ON posts.id = post_tags.post_id").
+		Where("posts.deleted_at IS NULL AND posts.visibility = ? AND posts.created_at >= ?", "public", since).
+		Group("tags.id, tags.name").
+		Order("count DESC, tags.name ASC").
+		Limit(limit).
+		Scan(&usage).Error
+	return usage, err
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"bad_boyes/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TokenRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+func (r *TokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *TokenRepository) GetRefreshTokenByHash(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	return &token, err
+}
+
+// RotateRefreshToken marks the old token as used and stores its replacement
+// in a single transaction.
+func (r *TokenRepository) RotateRefreshToken(old *models.RefreshToken, replacement *models.RefreshToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(replacement).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", old.ID).
+			Updates(map[string]interface{}{
+				"revoked_at":     now,
+				"replaced_by_id": replacement.ID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Another request rotated the token first
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// RevokeTokenFamily revokes every refresh token in the family and denylists
+// the access tokens that were issued alongside them.
+func (r *TokenRepository) RevokeTokenFamily(familyID string, accessExpiresAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var tokens []models.RefreshToken
+		if err := tx.Where("family_id = ?", familyID).Find(&tokens).Error; err != nil {
+			return err
+		}
+		return revokeTokens(tx, tokens, accessExpiresAt)
+	})
+}
+
+// RevokeUserTokens revokes every refresh token belonging to the user along
+// with their access tokens, ending all of the user's sessions.
+func (r *TokenRepository) RevokeUserTokens(userID uint, accessExpiresAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return revokeUserTokens(tx, userID, accessExpiresAt)
+	})
+}
+
+func revokeUserTokens(tx *gorm.DB, userID uint, accessExpiresAt time.Time) error {
+	var tokens []models.RefreshToken
+	if err := tx.Where("user_id = ?", userID).Find(&tokens).Error; err != nil {
+		return err
+	}
+	return revokeTokens(tx, tokens, accessExpiresAt)
+}
+
+func revokeTokens(tx *gorm.DB, tokens []models.RefreshToken, accessExpiresAt time.Time) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(tokens))
+	revoked := make([]models.RevokedToken, 0, len(tokens))
+	for _, t := range tokens {
+		ids = append(ids, t.ID)
+		revoked = append(revoked, models.RevokedToken{
+			JTI:       t.AccessTokenID,
+			UserID:    t.UserID,
+			ExpiresAt: accessExpiresAt,
+		})
+	}
+
+	if err := tx.Model(&models.RefreshToken{}).
+		Where("id IN ? AND revoked_at IS NULL", ids).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&revoked).Error
+}
+
+func (r *TokenRepository) RevokeAccessToken(token *models.RevokedToken) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+func (r *TokenRepository) IsAccessTokenRevoked(jti string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	return count > 0, err
+}
+
+// DeleteExpiredTokens removes denylist entries and refresh tokens that can no
+// longer be used.
+func (r *TokenRepository) DeleteExpiredTokens() error {
+	now := time.Now()
+	if err := r.db.Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+	return r.db.Where("expires_at < ?", now).Delete(&models.RefreshToken{}).Error
+}
+
+func (r *TokenRepository) RecordMFAFailure(failure *models.MFAFailure) error {
+	return r.db.Create(failure).Error
+}
+
+// CountMFAFailures returns how many wrong codes were entered for a challenge.
+func (r *TokenRepository) CountMFAFailures(challengeID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.MFAFailure{}).Where("challenge_id = ?", challengeID).Count(&count).Error
+	return count, err
+}
+
+// CountUserMFAFailures returns how many wrong codes a user entered since the
+// given time, over all challenges.
+func (r *TokenRepository) CountUserMFAFailures(userID uint, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.MFAFailure{}).Where("user_id = ? AND created_at >= ?", userID, since).Count(&count).Error
+	return count, err
+}
+
+func (r *TokenRepository) ClearMFAFailures(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.MFAFailure{}).Error
+}
+
+// DeleteMFAFailuresBefore removes failed attempts that no longer count
+// towards any limit.
+func (r *TokenRepository) DeleteMFAFailuresBefore(t time.Time) error {
+	return r.db.Where("created_at < ?", t).Delete(&models.MFAFailure{}).Error
+}
+
+func (r *TokenRepository) CreatePasswordResetToken(token *models.PasswordResetToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *TokenRepository) GetPasswordResetTokenByHash(hash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	return &token, err
+}
+
+// RedeemPasswordResetToken marks the token as used, sets the user's new
+// password and ends all of the user's sessions in a single transaction, so a
+// password is never changed without using up the token. It returns
+// gorm.ErrRecordNotFound when the token has already been used, so that a
+// token can only ever be redeemed once. The user's other outstanding reset
+// tokens are used up as well.
+func (r *TokenRepository) RedeemPasswordResetToken(token *models.PasswordResetToken, hashedPassword string, accessExpiresAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", token.UserID).Update("password", hashedPassword).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return revokeUserTokens(tx, token.UserID, accessExpiresAt)
+	})
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"bad_boyes/internal/models"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -20,7 +21,7 @@ func (r *UserRepository) CreateUser(user *models.User) error {
 
 func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := r.db.Preload("Roles").Where("email = ?", email).First(&user).Error
 	return &user, err
 }
 
@@ -35,3 +36,111 @@ func (r *UserRepository) UserExists(email string) (bool, error) {
 	err := r.db.Model(&models.User{}).Where("email = ?", email).Count(&count).Error
 	return count > 0, err
 }
+
+func (r *UserRepository) UpdatePassword(userID uint, hashedPassword string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Update("password", hashedPassword).Error
+}
+
+func (r *UserRepository) MarkEmailVerified(userID uint, verifiedAt time.Time) error {
+	return r.db.Model(&models.User{}).
+		Where("id = ? AND verified_at IS NULL", userID).
+		Update("verified_at", verifiedAt).Error
+}
+
+func (r *UserRepository) UpdateVerificationSentAt(userID uint, sentAt time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Update("verification_sent_at", sentAt).Error
+}
+
+// SetTOTPSecret stores a pending TOTP secret. Two-factor stays disabled until
+// the user confirms enrollment with a valid code.
+func (r *UserRepository) SetTOTPSecret(userID uint, secret string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":     secret,
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}).Error
+}
+
+func (r *UserRepository) EnableTOTP(userID uint, enabledAt time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Update("totp_enabled_at", enabledAt).Error
+}
+
+func (r *UserRepository) DisableTOTP(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":     nil,
+			"totp_enabled_at": nil,
+			"totp_last_step":  0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// UseTOTPStep records the time step of an accepted code. It returns
+// gorm.ErrRecordNotFound when the step was already used, which prevents the
+// same code from being replayed.
+func (r *UserRepository) UseTOTPStep(userID uint, step int64) error {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes deletes the user's recovery codes and stores new ones.
+func (r *UserRepository) ReplaceRecoveryCodes(userID uint, codes []models.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode marks an unused recovery code as used, returning
+// gorm.ErrRecordNotFound when there is no such code.
+func (r *UserRepository) UseRecoveryCode(userID uint, codeHash string) error {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Limit(1).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *UserRepository) CountUnusedRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// CreateUserWithRole creates the user and assigns the existing role with the
+// given name in a single transaction.
+func (r *UserRepository) CreateUserWithRole(user *models.User, roleName string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var role models.Role
+		if err := tx.Where("name = ?", roleName).First(&role).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("Roles").Create(user).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.UserRole{UserID: user.ID, RoleID: role.ID}).Error; err != nil {
+			return err
+		}
+		user.Roles = []models.Role{role}
+		return nil
+	})
+}
@@ -3,39 +3,113 @@ package routes
 import (
 	"bad_boyes/internal/handler"
 	"bad_boyes/internal/middleware"
+	"bad_boyes/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(r *gin.Engine, authHandler *handler.AuthHandler, postHandler *handler.PostHandler) {
+func SetupRoutes(r *gin.Engine, authService *services.AuthService, roleService *services.RoleService, authHandler *handler.AuthHandler, postHandler *handler.PostHandler, commentHandler *handler.CommentHandler, attachmentHandler *handler.AttachmentHandler, taxonomyHandler *handler.TaxonomyHandler, subjectHandler *handler.SubjectHandler, roleHandler *handler.RoleHandler) {
 	// Public routes
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
 	r.POST("/register", authHandler.Register)
 	r.POST("/login", authHandler.Login)
+	r.POST("/login/2fa", authHandler.LoginTwoFactor)
+	r.POST("/token/refresh", authHandler.RefreshToken)
+	r.POST("/password/forgot", authHandler.ForgotPassword)
+	r.GET("/password/reset", authHandler.ResetPasswordForm)
+	r.POST("/password/reset", authHandler.ResetPassword)
+	r.GET("/verify-email", authHandler.VerifyEmail)
+	r.POST("/verify-email", authHandler.VerifyEmail)
 
 	// Protected routes
 	auth := r.Group("/")
-	auth.Use(middleware.AuthMiddleware())
+	auth.Use(middleware.AuthMiddleware(authService))
 	{
 		// User profile route
 		auth.GET("/profile", authHandler.GetProfile)
+		auth.POST("/logout", authHandler.Logout)
+		auth.POST("/verify-email/resend", authHandler.ResendVerification)
+
+		// Two-factor authentication routes
+		auth.POST("/2fa/enroll", authHandler.EnrollTwoFactor)
+		auth.POST("/2fa/confirm", authHandler.ConfirmTwoFactor)
+		auth.POST("/2fa/disable", authHandler.DisableTwoFactor)
+		auth.POST("/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
 
 		// Post routes
-		auth.POST("/posts", postHandler.CreatePost)
-		auth.GET("/posts", postHandler.ListPosts)
-		auth.GET("/posts/:id", postHandler.GetPost)
-		auth.PUT("/posts/:id", postHandler.UpdatePost)
-		auth.DELETE("/posts/:id", postHandler.DeletePost)
-		auth.GET("/posts/:id/history", postHandler.GetPostHistory)
+		auth.POST("/posts", middleware.RequireVerifiedEmail(authService), middleware.RequirePermission(roleService, "posts", "create"), postHandler.CreatePost)
+		auth.GET("/posts", middleware.RequirePermission(roleService, "posts", "read"), postHandler.ListPosts)
+		auth.GET("/posts/search", middleware.RequirePermission(roleService, "posts", "read"), postHandler.SearchPosts)
+		auth.GET("/posts/nearby", middleware.RequirePermission(roleService, "posts", "read"), postHandler.NearbyPosts)
+		auth.GET("/posts/map", middleware.RequirePermission(roleService, "posts", "read"), postHandler.MapPosts)
+		auth.GET("/posts/trash", middleware.RequireAnyPermission(roleService, "posts", "restore:own", "restore:any"), postHandler.ListTrash)
+		auth.GET("/posts/:id", middleware.RequirePermission(roleService, "posts", "read"), postHandler.GetPost)
+		auth.PUT("/posts/:id", middleware.RequireAnyPermission(roleService, "posts", "update:own", "update:any"), postHandler.UpdatePost)
+		auth.PATCH("/posts/:id", middleware.RequireAnyPermission(roleService, "posts", "update:own", "update:any"), postHandler.PatchPost)
+		auth.DELETE("/posts/:id", middleware.RequireAnyPermission(roleService, "posts", "delete:own", "delete:any"), postHandler.DeletePost)
+		auth.POST("/posts/:id/restore", middleware.RequireAnyPermission(roleService, "posts", "restore:own", "restore:any"), postHandler.RestorePost)
+		auth.GET("/posts/:id/history", middleware.RequireAnyPermission(roleService, "posts", "history:own", "history:any"), postHandler.GetPostHistory)
+		auth.GET("/posts/:id/history/:rev/diff", middleware.RequireAnyPermission(roleService, "posts", "history:own", "history:any"), postHandler.GetRevisionDiff)
+		auth.POST("/posts/:id/revert/:rev", middleware.RequireAnyPermission(roleService, "posts", "update:own", "update:any"), postHandler.RevertPost)
+
+		// Comment routes
+		auth.GET("/posts/:id/comments", middleware.RequirePermission(roleService, "comments", "read"), commentHandler.ListComments)
+		auth.POST("/posts/:id/comments", middleware.RequireVerifiedEmail(authService), middleware.RequirePermission(roleService, "comments", "create"), commentHandler.CreateComment)
+		auth.PUT("/posts/:id/comments/:comment_id", middleware.RequireAnyPermission(roleService, "comments", "update:own", "update:any"), commentHandler.UpdateComment)
+		auth.DELETE("/posts/:id/comments/:comment_id", middleware.RequireAnyPermission(roleService, "comments", "delete:own", "delete:any"), commentHandler.DeleteComment)
+
+		// Attachment routes
+		auth.GET("/posts/:id/attachments", middleware.RequirePermission(roleService, "posts", "read"), attachmentHandler.ListAttachments)
+		auth.POST("/posts/:id/attachments", middleware.RequireVerifiedEmail(authService), middleware.RequireAnyPermission(roleService, "posts", "update:own", "update:any"), attachmentHandler.UploadAttachment)
+		auth.GET("/posts/:id/attachments/:attachment_id", middleware.RequirePermission(roleService, "posts", "read"), attachmentHandler.DownloadAttachment)
+		auth.GET("/posts/:id/attachments/:attachment_id/thumbnail", middleware.RequirePermission(roleService, "posts", "read"), attachmentHandler.DownloadThumbnail)
+		auth.DELETE("/posts/:id/attachments/:attachment_id", middleware.RequireAnyPermission(roleService, "posts", "update:own", "update:any"), attachmentHandler.DeleteAttachment)
+
+		// Taxonomy routes
+		auth.GET("/categories", taxonomyHandler.ListCategories)
+		auth.GET("/tags/popular", taxonomyHandler.PopularTags)
+
+		// Subject routes
+		auth.GET("/subjects/:id", middleware.RequirePermission(roleService, "subjects", "read"), subjectHandler.GetSubject)
 
 		// Report routes
-		auth.POST("/posts/:id/report", postHandler.CreateReport)
+		auth.POST("/posts/:id/report", middleware.RequireVerifiedEmail(authService), middleware.RequirePermission(roleService, "reports", "create"), postHandler.CreateReport)
 
 		// Admin routes
 		admin := auth.Group("/admin")
-		admin.Use(middleware.AdminMiddleware())
+		admin.Use(middleware.RequireMFA(authService))
 		{
-			admin.PUT("/reports/:id/status", postHandler.UpdateReportStatus)
-			admin.GET("/reports", postHandler.ListReports)
+			admin.PUT("/reports/:id/status", middleware.RequirePermission(roleService, "reports", "resolve"), postHandler.UpdateReportStatus)
+			admin.GET("/reports", middleware.RequirePermission(roleService, "reports", "read"), postHandler.ListReports)
+
+			// Category management
+			canManageCategories := middleware.RequirePermission(roleService, "categories", "manage")
+			admin.POST("/categories", canManageCategories, taxonomyHandler.CreateCategory)
+			admin.PUT("/categories/:id", canManageCategories, taxonomyHandler.UpdateCategory)
+			admin.DELETE("/categories/:id", canManageCategories, taxonomyHandler.DeleteCategory)
+
+			// Role and permission management
+			canReadRoles := middleware.RequirePermission(roleService, "roles", "read")
+			canManageRoles := middleware.RequirePermission(roleService, "roles", "manage")
+
+			admin.GET("/roles", canReadRoles, roleHandler.ListRoles)
+			admin.POST("/roles", canManageRoles, roleHandler.CreateRole)
+			admin.GET("/roles/:id", canReadRoles, roleHandler.GetRole)
+			admin.PUT("/roles/:id", canManageRoles, roleHandler.UpdateRole)
+			admin.DELETE("/roles/:id", canManageRoles, roleHandler.DeleteRole)
+			admin.PUT("/roles/:id/parent", canManageRoles, roleHandler.SetRoleParent)
+			admin.GET("/roles/:id/permissions", canReadRoles, roleHandler.GetRolePermissions)
+			admin.POST("/roles/:id/permissions", canManageRoles, roleHandler.AssignPermissionToRole)
+			admin.DELETE("/roles/:id/permissions/:permission_id", canManageRoles, roleHandler.RemovePermissionFromRole)
+
+			admin.GET("/permissions", canReadRoles, roleHandler.ListPermissions)
+			admin.POST("/permissions", canManageRoles, roleHandler.CreatePermission)
+			admin.PUT("/permissions/:id", canManageRoles, roleHandler.UpdatePermission)
+			admin.DELETE("/permissions/:id", canManageRoles, roleHandler.DeletePermission)
+
+			admin.GET("/users/:id/roles", canReadRoles, roleHandler.GetUserRoles)
+			admin.POST("/users/:id/roles", canManageRoles, roleHandler.AssignRoleToUser)
+			admin.DELETE("/users/:id/roles/:role_id", canManageRoles, roleHandler.RemoveRoleFromUser)
 		}
 	}
 }
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"bad_boyes/internal/config"
+	"bad_boyes/internal/media"
+	"bad_boyes/internal/models"
+	"bad_boyes/internal/repository"
+	"bad_boyes/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrAttachmentTooLarge = errors.New("attachment exceeds the maximum upload size")
+	ErrTooManyAttachments = errors.New("post has reached the maximum number of attachments")
+	ErrNoThumbnail        = errors.New("attachment has no thumbnail")
+)
+
+const (
+	defaultMaxAttachmentSize     = 10 << 20
+	defaultMaxAttachmentsPerPost = 10
+)
+
+type AttachmentService struct {
+	attachmentRepo *repository.AttachmentRepository
+	postRepo       *repository.PostRepository
+	auditRepo      *repository.AuditRepository
+	policy         *Policy
+	redactor       *redactor
+	storage        storage.Storage
+
+	maxSize    int64
+	maxPerPost int64
+}
+
+func NewAttachmentService(attachmentRepo *repository.AttachmentRepository, postRepo *repository.PostRepository, auditRepo *repository.AuditRepository, policy *Policy, store storage.Storage) *AttachmentService {
+	return &AttachmentService{
+		attachmentRepo: attachmentRepo,
+		postRepo:       postRepo,
+		auditRepo:      auditRepo,
+		policy:         policy,
+		redactor:       newRedactor(policy),
+		storage:        store,
+		maxSize:        config.GetInt64("ATTACHMENT_MAX_SIZE", defaultMaxAttachmentSize),
+		maxPerPost:     config.GetInt64("ATTACHMENT_MAX_PER_POST", defaultMaxAttachmentsPerPost),
+	}
+}
+
+// MaxSize is the largest file accepted, in bytes.
+func (s *AttachmentService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// UploadAttachment stores a file on a post the user may edit. The type is
+// sniffed from the content, images are stripped of their metadata and get
+// a thumbnail.
+func (s *AttachmentService) UploadAttachment(userID, postID uint, fileName string, r io.Reader) (*models.Attachment, error) {
+	post, err := s.postRepo.GetPostByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.AuthorizeOwned(userID, "posts", "update", post.UserID, post.ID); err != nil {
+		return nil, err
+	}
+
+	count, err := s.attachmentRepo.CountAttachments(postID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= s.maxPerPost {
+		return nil, ErrTooManyAttachments
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	contentType, ext, err := media.Sniff(data)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := &models.Attachment{
+		PostID:      postID,
+		UserID:      userID,
+		FileName:    sanitizeFileName(fileName, ext),
+		ContentType: contentType,
+	}
+
+	var thumbnail []byte
+	if media.IsImage(contentType) {
+		if attachment.Width, attachment.Height, err = media.Dimensions(data); err != nil {
+			return nil, err
+		}
+		if data, err = media.StripMetadata(contentType, data); err != nil {
+			return nil, err
+		}
+		if thumbnail, err = media.Thumbnail(data); err != nil {
+			return nil, err
+		}
+	}
+	attachment.Size = int64(len(data))
+
+	name, err := generateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	attachment.StorageKey = fmt.Sprintf("posts/%d/%s%s", postID, name, ext)
+
+	ctx := context.Background()
+	if err := s.storage.Put(ctx, attachment.StorageKey, data, contentType); err != nil {
+		log.Printf("Failed to store attachment for post %d: %v", postID, err)
+		return nil, err
+	}
+	if thumbnail != nil {
+		thumbnailKey := fmt.Sprintf("posts/%d/%s_thumb.jpg", postID, name)
+		if err := s.storage.Put(ctx, thumbnailKey, thumbnail, "image/jpeg"); err != nil {
+			s.deleteFiles(attachment)
+			return nil, err
+		}
+		attachment.ThumbnailKey = &thumbnailKey
+	}
+
+	if err := s.attachmentRepo.CreateAttachment(attachment); err != nil {
+		s.deleteFiles(attachment)
+		return nil, err
+	}
+
+	s.logAttachmentEvent(userID, "create_attachment", attachment.ID, nil, models.JSON{
+		"post_id":      postID,
+		"file_name":    attachment.FileName,
+		"content_type": attachment.ContentType,
+		"size":         attachment.Size,
+	})
+
+	setAttachmentURLs(attachment)
+	s.redactor.viewer(userID).attachment(post, attachment)
+	return attachment, nil
+}
+
+func (s *AttachmentService) ListAttachments(userID, postID uint) ([]models.Attachment, error) {
+	post, err := s.visiblePost(userID, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := s.attachmentRepo.ListAttachments(postID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		setAttachmentURLs(&attachments[i])
+	}
+	s.redactor.viewer(userID).attachments(post, attachments)
+	return attachments, nil
+}
+
+// OpenAttachment returns an attachment with a reader for its content, or
+// for its thumbnail. The caller must close the reader.
+func (s *AttachmentService) OpenAttachment(userID, postID, attachmentID uint, thumbnail bool) (*models.Attachment, io.ReadCloser, error) {
+	if _, err := s.visiblePost(userID, postID); err != nil {
+		return nil, nil, err
+	}
+
+	attachment, err := s.attachmentRepo.GetAttachment(postID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := attachment.StorageKey
+	if thumbnail {
+		if attachment.ThumbnailKey == nil {
+			return nil, nil, ErrNoThumbnail
+		}
+		key = *attachment.ThumbnailKey
+	}
+
+	reader, err := s.storage.Get(context.Background(), key)
+	if err != nil {
+		log.Printf("Failed to read attachment %d from storage: %v", attachment.ID, err)
+		return nil, nil, err
+	}
+	return attachment, reader, nil
+}
+
+func (s *AttachmentService) DeleteAttachment(userID, postID, attachmentID uint) error {
+	post, err := s.postRepo.GetPostByID(postID)
+	if err != nil {
+		return err
+	}
+	if err := s.policy.AuthorizeOwned(userID, "posts", "update", post.UserID, post.ID); err != nil {
+		return err
+	}
+
+	attachment, err := s.attachmentRepo.GetAttachment(postID, attachmentID)
+	if err != nil {
+		return err
+	}
+	if err := s.attachmentRepo.DeleteAttachment(attachment.ID); err != nil {
+		return err
+	}
+	s.deleteFiles(attachment)
+
+	s.logAttachmentEvent(userID, "delete_attachment", attachment.ID, models.JSON{
+		"post_id":      postID,
+		"file_name":    attachment.FileName,
+		"content_type": attachment.ContentType,
+		"size":         attachment.Size,
+	}, nil)
+	return nil
+}
+
+// DeletePostFiles removes the stored files of posts that are about to be
+// purged. It is registered with PostService.OnPurge.
+func (s *AttachmentService) DeletePostFiles(postIDs []uint) error {
+	attachments, err := s.attachmentRepo.ListAttachmentsForPosts(postIDs)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for _, attachment := range attachments {
+		if err := s.storage.Delete(ctx, attachment.StorageKey); err != nil {
+			return err
+		}
+		if attachment.ThumbnailKey != nil {
+			if err := s.storage.Delete(ctx, *attachment.ThumbnailKey); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *AttachmentService) visiblePost(userID, postID uint) (*models.Post, error) {
+	if err := s.policy.Authorize(userID, "posts", "read"); err != nil {
+		return nil, err
+	}
+	post, err := s.postRepo.GetPostByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.AuthorizePostView(userID, post); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// deleteFiles removes the stored files of an attachment, logging failures
+// since the database row is already gone or was never written.
+func (s *AttachmentService) deleteFiles(attachment *models.Attachment) {
+	ctx := context.Background()
+	if err := s.storage.Delete(ctx, attachment.StorageKey); err != nil {
+		log.Printf("Failed to delete stored file %s: %v", attachment.StorageKey, err)
+	}
+	if attachment.ThumbnailKey != nil {
+		if err := s.storage.Delete(ctx, *attachment.ThumbnailKey); err != nil {
+			log.Printf("Failed to delete stored file %s: %v", *attachment.ThumbnailKey, err)
+		}
+	}
+}
+
+func setAttachmentURLs(attachment *models.Attachment) {
+	attachment.URL = fmt.Sprintf("/posts/%d/attachments/%d", attachment.PostID, attachment.ID)
+	if attachment.ThumbnailKey != nil {
+		attachment.ThumbnailURL = attachment.URL + "/thumbnail"
+	}
+}
+
+// sanitizeFileName keeps the base name of an uploaded file without control
+// characters, and makes its extension match the sniffed type.
+func sanitizeFileName(name, ext string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if name == "" || name == "." || name == "/" {
+		name = "attachment"
+	}
+	if runes := []rune(name); len(runes) > 200 {
+		name = string(runes[:200])
+	}
+	return name + ext
+}
+
+func (s *AttachmentService) logAttachmentEvent(userID uint, action string, recordID uint, oldValues, newValues models.JSON) {
+	auditLog := &models.AuditLog{
+		UserID:    &userID,
+		Action:    action,
+		TableName: "attachments",
+		RecordID:  recordID,
+		OldValues: oldValues,
+		NewValues: newValues,
+	}
+	if err := s.auditRepo.CreateLog(auditLog); err != nil {
+		log.Printf("Failed to create audit log for %s on attachment %d: %v", action, recordID, err)
+	}
+}
@@ -1,9 +1,17 @@
 package services
 
 import (
+	"bad_boyes/internal/config"
+	"bad_boyes/internal/mail"
 	"bad_boyes/internal/models"
 	"bad_boyes/internal/repository"
+	"bad_boyes/internal/signing"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"log"
 	"os"
 	"time"
@@ -19,38 +27,161 @@ var (
 	ErrUserAlreadyExists = errors.New("user already exists")
 	ErrJWTSecretMissing  = errors.New("JWT secret is not configured")
 	ErrDatabaseError     = errors.New("database error occurred")
+
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token has expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+
+	ErrInvalidResetToken = errors.New("invalid or already used password reset token")
+	ErrResetTokenExpired = errors.New("password reset token has expired")
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+	defaultResetTokenTTL   = time.Hour
+	defaultAppURL          = "http://localhost:3003"
+	defaultRoleName        = "user"
 )
 
+// userRepository is the user storage AuthService works with, implemented by
+// *repository.UserRepository.
+type userRepository interface {
+	CreateUserWithRole(user *models.User, roleName string) error
+	GetUserByEmail(email string) (*models.User, error)
+	GetUserByID(id uint) (*models.User, error)
+	UserExists(email string) (bool, error)
+	MarkEmailVerified(userID uint, verifiedAt time.Time) error
+	UpdateVerificationSentAt(userID uint, sentAt time.Time) error
+	SetTOTPSecret(userID uint, secret string) error
+	EnableTOTP(userID uint, enabledAt time.Time) error
+	DisableTOTP(userID uint) error
+	UseTOTPStep(userID uint, step int64) error
+	ReplaceRecoveryCodes(userID uint, codes []models.RecoveryCode) error
+	UseRecoveryCode(userID uint, codeHash string) error
+}
+
+// tokenRepository is the token storage AuthService works with, implemented
+// by *repository.TokenRepository.
+type tokenRepository interface {
+	CreateRefreshToken(token *models.RefreshToken) error
+	GetRefreshTokenByHash(hash string) (*models.RefreshToken, error)
+	RotateRefreshToken(old *models.RefreshToken, replacement *models.RefreshToken) error
+	RevokeTokenFamily(familyID string, accessExpiresAt time.Time) error
+	RevokeAccessToken(token *models.RevokedToken) error
+	IsAccessTokenRevoked(jti string) (bool, error)
+	DeleteExpiredTokens() error
+	RecordMFAFailure(failure *models.MFAFailure) error
+	CountMFAFailures(challengeID string) (int64, error)
+	CountUserMFAFailures(userID uint, since time.Time) (int64, error)
+	ClearMFAFailures(userID uint) error
+	DeleteMFAFailuresBefore(t time.Time) error
+	CreatePasswordResetToken(token *models.PasswordResetToken) error
+	GetPasswordResetTokenByHash(hash string) (*models.PasswordResetToken, error)
+	RedeemPasswordResetToken(token *models.PasswordResetToken, hashedPassword string, accessExpiresAt time.Time) error
+}
+
 type AuthService struct {
-	userRepo  *repository.UserRepository
-	auditRepo *repository.AuditRepository
+	userRepo        userRepository
+	auditRepo       *repository.AuditRepository
+	tokenRepo       tokenRepository
+	mailer          mail.Sender
+	keys            *signing.KeyStore
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	resetTokenTTL   time.Duration
+	appURL          string
+
+	verificationSecret   []byte
+	verificationTTL      time.Duration
+	verificationCooldown time.Duration
+	requireVerifiedEmail bool
+
+	mfaChallengeTTL time.Duration
+	mfaIssuer       string
+	requireAdminMFA bool
+
+	// mfaMaxAttempts wrong codes revoke a challenge; mfaMaxUserFailures
+	// wrong codes within mfaLockoutWindow block every second factor check
+	// of the user.
+	mfaMaxAttempts     int64
+	mfaMaxUserFailures int64
+	mfaLockoutWindow   time.Duration
 }
 
+// Values of the token_use claim, which keeps MFA challenge tokens from being
+// accepted as access tokens.
+const (
+	TokenUseAccess       = "access"
+	TokenUseMFAChallenge = "mfa_challenge"
+)
+
 type LoginResponse struct {
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
-	UserID    uint      `json:"user_id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	Role      string    `json:"role"`
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	UserID           uint      `json:"user_id"`
+	Username         string    `json:"username"`
+	Email            string    `json:"email"`
+	Name             string    `json:"name"`
+	Role             string    `json:"role"`
+
+	// Set instead of the tokens above when a second factor is required
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+
+	// Set when policy requires this user to enroll in two-factor auth
+	MFAEnrollmentRequired bool `json:"mfa_enrollment_required,omitempty"`
 }
 
 type UserProfileResponse struct {
-	ID        uint      `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	Birthday  time.Time `json:"birthday"`
-	Roles     []string  `json:"roles"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID               uint       `json:"id"`
+	Username         string     `json:"username"`
+	Email            string     `json:"email"`
+	Name             string     `json:"name"`
+	Birthday         time.Time  `json:"birthday"`
+	Roles            []string   `json:"roles"`
+	VerifiedAt       *time.Time `json:"verified_at"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
 }
 
-func NewAuthService(userRepo *repository.UserRepository, auditRepo *repository.AuditRepository) *AuthService {
+func NewAuthService(userRepo *repository.UserRepository, auditRepo *repository.AuditRepository, tokenRepo *repository.TokenRepository, mailer mail.Sender, keys *signing.KeyStore, verificationSecret []byte) *AuthService {
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = defaultAppURL
+	}
+
+	mfaIssuer := os.Getenv("MFA_ISSUER")
+	if mfaIssuer == "" {
+		mfaIssuer = defaultMFAIssuer
+	}
+
 	return &AuthService{
-		userRepo:  userRepo,
-		auditRepo: auditRepo,
+		userRepo:        userRepo,
+		auditRepo:       auditRepo,
+		tokenRepo:       tokenRepo,
+		mailer:          mailer,
+		keys:            keys,
+		accessTokenTTL:  config.GetDuration("ACCESS_TOKEN_TTL", defaultAccessTokenTTL),
+		refreshTokenTTL: config.GetDuration("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL),
+		resetTokenTTL:   config.GetDuration("PASSWORD_RESET_TTL", defaultResetTokenTTL),
+		appURL:          appURL,
+
+		verificationSecret:   verificationSecret,
+		verificationTTL:      config.GetDuration("EMAIL_VERIFICATION_TTL", defaultVerificationTTL),
+		verificationCooldown: config.GetDuration("EMAIL_VERIFICATION_COOLDOWN", defaultVerificationCooldown),
+		requireVerifiedEmail: config.GetBool("REQUIRE_VERIFIED_EMAIL", true),
+
+		mfaChallengeTTL: config.GetDuration("MFA_CHALLENGE_TTL", defaultMFAChallengeTTL),
+		mfaIssuer:       mfaIssuer,
+		requireAdminMFA: config.GetBool("REQUIRE_ADMIN_2FA", false),
+
+		mfaMaxAttempts:     config.GetInt64("MFA_MAX_ATTEMPTS", defaultMFAMaxAttempts),
+		mfaMaxUserFailures: config.GetInt64("MFA_MAX_USER_FAILURES", defaultMFAMaxUserFailures),
+		mfaLockoutWindow:   config.GetDuration("MFA_LOCKOUT_WINDOW", defaultMFALockoutWindow),
 	}
 }
 
@@ -79,14 +210,18 @@ func (s *AuthService) Register(req models.RegisterRequest) error {
 		Password: string(hashedPassword),
 		Name:     req.Name,
 		Birthday: req.Birthday,
-		Roles:    []models.Role{{Name: "user"}}, // Default role
 	}
 
-	if err := s.userRepo.CreateUser(user); err != nil {
+	if err := s.userRepo.CreateUserWithRole(user, defaultRoleName); err != nil {
 		log.Printf("Error creating user: %v", err)
 		return ErrDatabaseError
 	}
 
+	if err := s.sendVerificationEmail(user); err != nil {
+		// The user can request a new link, so registration still succeeds
+		log.Printf("Failed to send verification email to %s: %v", req.Email, err)
+	}
+
 	log.Printf("User registered successfully: %s", req.Email)
 	return nil
 }
@@ -110,23 +245,301 @@ func (s *AuthService) Login(req models.LoginRequest) (*LoginResponse, error) {
 		return nil, ErrInvalidPassword
 	}
 
-	expiresAt := time.Now().Add(time.Hour * 24)
+	// Users with two-factor enabled only get a challenge token at this point
+	if user.TOTPEnabledAt != nil {
+		log.Printf("Password accepted for %s, two-factor authentication required", req.Email)
+		return s.startMFAChallenge(user)
+	}
+
+	response, err := s.startSession(user, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.requireAdminMFA && hasRole(user, "admin") {
+		response.MFAEnrollmentRequired = true
+	}
+
+	log.Printf("Login successful for user: %s", user.Email)
+	return response, nil
+}
+
+// startSession issues the first access/refresh token pair of a new token
+// family. mfa records whether a second factor was presented.
+func (s *AuthService) startSession(user *models.User, mfa bool) (*LoginResponse, error) {
+	familyID, err := generateRandomToken(16)
+	if err != nil {
+		log.Printf("Error generating token family: %v", err)
+		return nil, errors.New("error generating authentication token")
+	}
+
+	return s.issueTokens(user, familyID, mfa)
+}
+
+// RefreshToken exchanges a refresh token for a new access/refresh token pair.
+// The presented token is consumed; presenting it again revokes the whole
+// token family since it means the token has leaked.
+func (s *AuthService) RefreshToken(refreshToken string) (*LoginResponse, error) {
+	stored, err := s.tokenRepo.GetRefreshTokenByHash(hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Refresh failed: unknown refresh token")
+			return nil, ErrInvalidRefreshToken
+		}
+		log.Printf("Database error while loading refresh token: %v", err)
+		return nil, ErrDatabaseError
+	}
+
+	if stored.RevokedAt != nil {
+		log.Printf("Refresh token reuse detected for user %d, revoking family %s", stored.UserID, stored.FamilyID)
+		if err := s.tokenRepo.RevokeTokenFamily(stored.FamilyID, time.Now().Add(s.accessTokenTTL)); err != nil {
+			log.Printf("Failed to revoke token family %s: %v", stored.FamilyID, err)
+		}
+		s.logAuthEvent(stored.UserID, "refresh_token_reuse", "refresh_tokens", stored.ID, models.JSON{"family_id": stored.FamilyID})
+		return nil, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		log.Printf("Refresh failed: token %d expired at %s", stored.ID, stored.ExpiresAt)
+		return nil, ErrRefreshTokenExpired
+	}
+
+	user, err := s.userRepo.GetUserByID(stored.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		log.Printf("Database error while loading user for refresh: %v", err)
+		return nil, ErrDatabaseError
+	}
+
+	response, replacement, err := s.buildTokens(user, stored.FamilyID, stored.MFA)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.tokenRepo.RotateRefreshToken(stored, replacement); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Lost a race with a concurrent refresh of the same token
+			log.Printf("Refresh token %d was rotated concurrently, revoking family %s", stored.ID, stored.FamilyID)
+			if err := s.tokenRepo.RevokeTokenFamily(stored.FamilyID, time.Now().Add(s.accessTokenTTL)); err != nil {
+				log.Printf("Failed to revoke token family %s: %v", stored.FamilyID, err)
+			}
+			return nil, ErrRefreshTokenReused
+		}
+		log.Printf("Error rotating refresh token: %v", err)
+		return nil, ErrDatabaseError
+	}
+
+	log.Printf("Refresh token rotated for user: %s", user.Email)
+	return response, nil
+}
+
+// Logout revokes the current access token and, when supplied, the refresh
+// token family it belongs to.
+func (s *AuthService) Logout(userID uint, jti string, accessExpiresAt time.Time, refreshToken string) error {
+	if jti != "" {
+		if err := s.tokenRepo.RevokeAccessToken(&models.RevokedToken{
+			JTI:       jti,
+			UserID:    userID,
+			ExpiresAt: accessExpiresAt,
+		}); err != nil {
+			log.Printf("Failed to revoke access token %s: %v", jti, err)
+			return ErrDatabaseError
+		}
+	}
+
+	if refreshToken != "" {
+		stored, err := s.tokenRepo.GetRefreshTokenByHash(hashToken(refreshToken))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			log.Printf("Database error while loading refresh token: %v", err)
+			return ErrDatabaseError
+		}
+		if stored.UserID != userID {
+			return ErrInvalidRefreshToken
+		}
+		if err := s.tokenRepo.RevokeTokenFamily(stored.FamilyID, time.Now().Add(s.accessTokenTTL)); err != nil {
+			log.Printf("Failed to revoke token family %s: %v", stored.FamilyID, err)
+			return ErrDatabaseError
+		}
+	}
+
+	s.logAuthEvent(userID, "logout", "users", userID, nil)
+	log.Printf("User %d logged out", userID)
+	return nil
+}
+
+// ForgotPassword emails a password reset link to the given address. To avoid
+// revealing which emails are registered, unknown addresses and failures to
+// send the email are not treated as an error.
+func (s *AuthService) ForgotPassword(email string) error {
+	log.Printf("Password reset requested for email: %s", email)
+
+	user, err := s.userRepo.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Password reset requested for unknown email %s", email)
+			return nil
+		}
+		log.Printf("Database error during password reset request: %v", err)
+		return ErrDatabaseError
+	}
+
+	token, err := generateRandomToken(32)
+	if err != nil {
+		log.Printf("Error generating password reset token: %v", err)
+		return errors.New("error generating password reset token")
+	}
+
+	resetToken := &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.resetTokenTTL),
+	}
+	if err := s.tokenRepo.CreatePasswordResetToken(resetToken); err != nil {
+		log.Printf("Error storing password reset token: %v", err)
+		return ErrDatabaseError
+	}
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to reset your password. It expires in %s and can only be used once.\n\n%s/password/reset?token=%s\n\nIf you did not request a password reset you can ignore this email.",
+			user.Name, s.resetTokenTTL, s.appURL, token),
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		// Answer as for any other address, so failures do not reveal
+		// which emails have an account
+		log.Printf("Failed to send password reset email to %s: %v", user.Email, err)
+		return nil
+	}
+
+	s.logAuthEvent(user.ID, "password_reset_requested", "password_reset_tokens", resetToken.ID, models.JSON{
+		"expires_at": resetToken.ExpiresAt,
+	})
+
+	log.Printf("Password reset email sent to: %s", user.Email)
+	return nil
+}
+
+// ResetPassword redeems a password reset token, sets the new password and
+// ends every existing session of the user.
+func (s *AuthService) ResetPassword(token, newPassword string) error {
+	resetToken, err := s.tokenRepo.GetPasswordResetTokenByHash(hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Password reset failed: unknown token")
+			return ErrInvalidResetToken
+		}
+		log.Printf("Database error while loading password reset token: %v", err)
+		return ErrDatabaseError
+	}
+
+	if resetToken.UsedAt != nil {
+		log.Printf("Password reset failed: token %d already used", resetToken.ID)
+		return ErrInvalidResetToken
+	}
+	if time.Now().After(resetToken.ExpiresAt) {
+		log.Printf("Password reset failed: token %d expired at %s", resetToken.ID, resetToken.ExpiresAt)
+		return ErrResetTokenExpired
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		return errors.New("error processing password")
+	}
+
+	if err := s.tokenRepo.RedeemPasswordResetToken(resetToken, string(hashedPassword), time.Now().Add(s.accessTokenTTL)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		log.Printf("Error resetting password for user %d: %v", resetToken.UserID, err)
+		return ErrDatabaseError
+	}
+
+	s.logAuthEvent(resetToken.UserID, "password_reset", "users", resetToken.UserID, models.JSON{
+		"reset_token_id": resetToken.ID,
+	})
+
+	log.Printf("Password reset completed for user %d", resetToken.UserID)
+	return nil
+}
+
+// StartTokenCleanupJob removes expired refresh tokens, access token
+// denylist entries and failed MFA attempts every interval for the lifetime
+// of the process.
+func (s *AuthService) StartTokenCleanupJob(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := s.tokenRepo.DeleteExpiredTokens(); err != nil {
+				log.Printf("Failed to delete expired tokens: %v", err)
+			}
+			// Failures count per challenge until it expires and per user
+			// within the lockout window
+			keep := s.mfaLockoutWindow
+			if s.mfaChallengeTTL > keep {
+				keep = s.mfaChallengeTTL
+			}
+			if err := s.tokenRepo.DeleteMFAFailuresBefore(time.Now().Add(-keep)); err != nil {
+				log.Printf("Failed to delete old MFA failures: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// IsTokenRevoked reports whether the access token with the given jti has been
+// revoked.
+func (s *AuthService) IsTokenRevoked(jti string) (bool, error) {
+	return s.tokenRepo.IsAccessTokenRevoked(jti)
+}
+
+// issueTokens signs a new access token, persists a matching refresh token in
+// the given family and returns both to the caller.
+func (s *AuthService) issueTokens(user *models.User, familyID string, mfa bool) (*LoginResponse, error) {
+	response, refresh, err := s.buildTokens(user, familyID, mfa)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.tokenRepo.CreateRefreshToken(refresh); err != nil {
+		log.Printf("Error storing refresh token: %v", err)
+		return nil, ErrDatabaseError
+	}
+
+	return response, nil
+}
+
+// buildTokens signs a new access token and prepares, without saving, the
+// refresh token that accompanies it.
+func (s *AuthService) buildTokens(user *models.User, familyID string, mfa bool) (*LoginResponse, *models.RefreshToken, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.accessTokenTTL)
 	log.Printf("Generating JWT token for user %s, expires at: %s", user.Email, expiresAt)
 
-	// Get JWT secret from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Printf("Login failed: JWT_SECRET environment variable is not set")
-		return nil, ErrJWTSecretMissing
+	jti, err := generateRandomToken(16)
+	if err != nil {
+		log.Printf("Error generating token ID: %v", err)
+		return nil, nil, errors.New("error generating authentication token")
 	}
 
 	// Create JWT claims
 	claims := jwt.MapClaims{
-		"user_id":  user.ID,
-		"username": user.Username,
-		"email":    user.Email,
-		"name":     user.Name,
-		"exp":      expiresAt.Unix(),
+		"jti":       jti,
+		"token_use": TokenUseAccess,
+		"user_id":   user.ID,
+		"username":  user.Username,
+		"email":     user.Email,
+		"name":      user.Name,
+		"mfa":       mfa,
+		"iat":       now.Unix(),
+		"exp":       expiresAt.Unix(),
 	}
 
 	// Add role if user has roles
@@ -134,24 +547,36 @@ func (s *AuthService) Login(req models.LoginRequest) (*LoginResponse, error) {
 		claims["role"] = user.Roles[0].Name
 	}
 
-	// Create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := s.signToken(claims)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	// Sign token
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	refreshToken, err := generateRandomToken(32)
 	if err != nil {
-		log.Printf("Error signing JWT token: %v", err)
-		return nil, errors.New("error generating authentication token")
+		log.Printf("Error generating refresh token: %v", err)
+		return nil, nil, errors.New("error generating authentication token")
+	}
+
+	refresh := &models.RefreshToken{
+		UserID:        user.ID,
+		TokenHash:     hashToken(refreshToken),
+		FamilyID:      familyID,
+		AccessTokenID: jti,
+		MFA:           mfa,
+		ExpiresAt:     now.Add(s.refreshTokenTTL),
 	}
 
 	// Create response
-	response := LoginResponse{
-		Token:     tokenString,
-		ExpiresAt: expiresAt,
-		UserID:    user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		Name:      user.Name,
+	response := &LoginResponse{
+		Token:            tokenString,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refresh.ExpiresAt,
+		UserID:           user.ID,
+		Username:         user.Username,
+		Email:            user.Email,
+		Name:             user.Name,
 	}
 
 	// Add role to response if user has roles
@@ -159,8 +584,59 @@ func (s *AuthService) Login(req models.LoginRequest) (*LoginResponse, error) {
 		response.Role = user.Roles[0].Name
 	}
 
-	log.Printf("Login successful for user: %s", user.Email)
-	return &response, nil
+	return response, refresh, nil
+}
+
+// logAuthEvent writes an audit log entry for an authentication event. Failures
+// are logged but never fail the request.
+func (s *AuthService) logAuthEvent(userID uint, action, tableName string, recordID uint, values models.JSON) {
+	auditLog := &models.AuditLog{
+		UserID:    &userID,
+		Action:    action,
+		TableName: tableName,
+		RecordID:  recordID,
+		NewValues: values,
+	}
+	if err := s.auditRepo.CreateLog(auditLog); err != nil {
+		log.Printf("Failed to create audit log for %s: %v", action, err)
+	}
+}
+
+// signToken signs the claims with the active signing key.
+func (s *AuthService) signToken(claims jwt.MapClaims) (string, error) {
+	tokenString, err := s.keys.Sign(claims)
+	if err != nil {
+		log.Printf("Error signing JWT token: %v", err)
+		return "", errors.New("error generating authentication token")
+	}
+	return tokenString, nil
+}
+
+// ParseToken verifies the signature and expiry of a token issued by this
+// service and returns its claims.
+func (s *AuthService) ParseToken(tokenString string) (jwt.MapClaims, error) {
+	return s.keys.Parse(tokenString)
+}
+
+// JWKS returns the public keys other services can use to verify tokens.
+func (s *AuthService) JWKS() signing.JWKS {
+	return s.keys.JWKS()
+}
+
+// generateRandomToken returns n random bytes encoded as URL-safe base64.
+func generateRandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex-encoded SHA-256 digest used to store opaque
+// tokens without keeping the raw value.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 func (s *AuthService) GetUserProfile(userID uint) (*UserProfileResponse, error) {
@@ -185,14 +661,16 @@ func (s *AuthService) GetUserProfile(userID uint) (*UserProfileResponse, error)
 	}
 
 	response := &UserProfileResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		Name:      user.Name,
-		Birthday:  time.Time(user.Birthday),
-		Roles:     roles,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:               user.ID,
+		Username:         user.Username,
+		Email:            user.Email,
+		Name:             user.Name,
+		Birthday:         time.Time(user.Birthday),
+		Roles:            roles,
+		VerifiedAt:       user.VerifiedAt,
+		TwoFactorEnabled: user.TOTPEnabledAt != nil,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
 	}
 
 	log.Printf("Created profile response: %+v", response)
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"bad_boyes/internal/models"
+	"bad_boyes/internal/repository"
+	"errors"
+	"log"
+)
+
+var (
+	ErrCommentsDisabled   = errors.New("comments are disabled for this post")
+	ErrInvalidParent      = errors.New("parent comment does not belong to this post")
+	ErrCommentNotEditable = errors.New("comment has been deleted")
+)
+
+const (
+	defaultCommentPageSize = 20
+	maxCommentPageSize     = 100
+)
+
+type CommentService struct {
+	commentRepo *repository.CommentRepository
+	postRepo    *repository.PostRepository
+	auditRepo   *repository.AuditRepository
+	policy      *Policy
+}
+
+func NewCommentService(commentRepo *repository.CommentRepository, postRepo *repository.PostRepository, auditRepo *repository.AuditRepository, policy *Policy) *CommentService {
+	return &CommentService{
+		commentRepo: commentRepo,
+		postRepo:    postRepo,
+		auditRepo:   auditRepo,
+		policy:      policy,
+	}
+}
+
+// ListComments returns a page of top-level comments on a post with their
+// replies nested below them.
+func (s *CommentService) ListComments(userID, postID uint, page, pageSize int) ([]*models.Comment, int64, error) {
+	if err := s.policy.Authorize(userID, "comments", "read"); err != nil {
+		return nil, 0, err
+	}
+	if _, err := s.visiblePost(userID, postID); err != nil {
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultCommentPageSize
+	}
+	if pageSize > maxCommentPageSize {
+		pageSize = maxCommentPageSize
+	}
+
+	roots, replies, total, err := s.commentRepo.ListThreads(postID, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	return buildThreads(roots, replies), total, nil
+}
+
+func (s *CommentService) CreateComment(userID, postID uint, req models.CreateCommentRequest) (*models.Comment, error) {
+	if err := s.policy.Authorize(userID, "comments", "create"); err != nil {
+		return nil, err
+	}
+
+	post, err := s.visiblePost(userID, postID)
+	if err != nil {
+		return nil, err
+	}
+	if !post.AllowComments {
+		return nil, ErrCommentsDisabled
+	}
+
+	if req.ParentID != nil {
+		parent, err := s.commentRepo.GetComment(postID, *req.ParentID)
+		if err != nil {
+			return nil, ErrInvalidParent
+		}
+		if parent.Status != models.CommentStatusVisible {
+			return nil, ErrCommentNotEditable
+		}
+	}
+
+	comment := &models.Comment{
+		PostID:   postID,
+		UserID:   userID,
+		ParentID: req.ParentID,
+		Body:     req.Body,
+		Status:   models.CommentStatusVisible,
+	}
+	if err := s.commentRepo.CreateComment(comment); err != nil {
+		log.Printf("Failed to create comment on post %d: %v", postID, err)
+		return nil, err
+	}
+
+	s.logCommentEvent(userID, "create_comment", comment.ID, nil, models.JSON{
+		"post_id":   postID,
+		"parent_id": req.ParentID,
+		"body":      comment.Body,
+	})
+	return comment, nil
+}
+
+// UpdateComment lets the author edit the body of a comment that has not been
+// deleted or removed.
+func (s *CommentService) UpdateComment(userID, postID, commentID uint, req models.UpdateCommentRequest) (*models.Comment, error) {
+	if _, err := s.visiblePost(userID, postID); err != nil {
+		return nil, err
+	}
+
+	comment, err := s.commentRepo.GetComment(postID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.AuthorizeOwned(userID, "comments", "update", comment.UserID, comment.ID); err != nil {
+		return nil, err
+	}
+	if comment.Status != models.CommentStatusVisible {
+		return nil, ErrCommentNotEditable
+	}
+
+	oldBody := comment.Body
+	if err := s.commentRepo.UpdateCommentBody(comment.ID, req.Body); err != nil {
+		return nil, err
+	}
+
+	s.logCommentEvent(userID, "update_comment", comment.ID,
+		models.JSON{"body": oldBody},
+		models.JSON{"body": req.Body})
+	return s.commentRepo.GetComment(postID, commentID)
+}
+
+// DeleteComment hides a comment. Authors delete their own comments, users
+// holding comments:delete:any remove other people's.
+func (s *CommentService) DeleteComment(userID, postID, commentID uint) error {
+	if _, err := s.visiblePost(userID, postID); err != nil {
+		return err
+	}
+
+	comment, err := s.commentRepo.GetComment(postID, commentID)
+	if err != nil {
+		return err
+	}
+	if err := s.policy.AuthorizeOwned(userID, "comments", "delete", comment.UserID, comment.ID); err != nil {
+		return err
+	}
+
+	status, action := models.CommentStatusDeleted, "delete_comment"
+	if comment.UserID != userID {
+		status, action = models.CommentStatusRemoved, "remove_comment"
+	}
+	if err := s.commentRepo.SetCommentStatus(comment.ID, status); err != nil {
+		return err
+	}
+
+	s.logCommentEvent(userID, action, comment.ID,
+		models.JSON{"status": comment.Status, "body": comment.Body},
+		models.JSON{"status": status})
+	return nil
+}
+
+func (s *CommentService) visiblePost(userID, postID uint) (*models.Post, error) {
+	post, err := s.postRepo.GetPostByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.AuthorizePostView(userID, post); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// buildThreads nests replies under their parents. The body of deleted and
+// removed comments is blanked so the thread structure stays readable.
+func buildThreads(roots, replies []models.Comment) []*models.Comment {
+	byID := make(map[uint]*models.Comment, len(roots)+len(replies))
+	threads := make([]*models.Comment, 0, len(roots))
+
+	for i := range roots {
+		comment := &roots[i]
+		redactComment(comment)
+		byID[comment.ID] = comment
+		threads = append(threads, comment)
+	}
+	for i := range replies {
+		byID[replies[i].ID] = &replies[i]
+	}
+	// replies are ordered by creation, so siblings keep that order
+	for i := range replies {
+		comment := &replies[i]
+		redactComment(comment)
+		if parent, ok := byID[*comment.ParentID]; ok {
+			parent.Replies = append(parent.Replies, comment)
+		}
+	}
+	return threads
+}
+
+func redactComment(comment *models.Comment) {
+	if comment.Status != models.CommentStatusVisible {
+		comment.Body = ""
+		comment.User = nil
+	}
+}
+
+func (s *CommentService) logCommentEvent(userID uint, action string, recordID uint, oldValues, newValues models.JSON) {
+	auditLog := &models.AuditLog{
+		UserID:    &userID,
+		Action:    action,
+		TableName: "comments",
+		RecordID:  recordID,
+		OldValues: oldValues,
+		NewValues: newValues,
+	}
+	if err := s.auditRepo.CreateLog(auditLog); err != nil {
+		log.Printf("Failed to create audit log for %s on comment %d: %v", action, recordID, err)
+	}
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"bad_boyes/internal/mail"
+	"bad_boyes/internal/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidVerificationToken  = errors.New("invalid email verification token")
+	ErrVerificationTokenExpired  = errors.New("email verification token has expired")
+	ErrEmailAlreadyVerified      = errors.New("email is already verified")
+	ErrVerificationCooldown      = errors.New("verification email was sent recently, please try again later")
+	ErrEmailNotVerified          = errors.New("email address has not been verified")
+	ErrVerificationSecretMissing = errors.New("EMAIL_VERIFICATION_SECRET is not set")
+)
+
+const (
+	defaultVerificationTTL      = 48 * time.Hour
+	defaultVerificationCooldown = time.Minute
+)
+
+// VerifyEmail marks the account referenced by a signed verification token as
+// verified. Verifying an already verified account is a no-op.
+func (s *AuthService) VerifyEmail(token string) error {
+	userID, email, err := s.parseVerificationToken(token)
+	if err != nil {
+		log.Printf("Email verification failed: %v", err)
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		log.Printf("Database error during email verification: %v", err)
+		return ErrDatabaseError
+	}
+
+	// A token issued for a previous address must not verify the current one
+	if !strings.EqualFold(user.Email, email) {
+		log.Printf("Email verification failed: token email does not match user %d", userID)
+		return ErrInvalidVerificationToken
+	}
+
+	if user.VerifiedAt != nil {
+		return nil
+	}
+
+	if err := s.userRepo.MarkEmailVerified(user.ID, time.Now()); err != nil {
+		log.Printf("Error marking email verified for user %d: %v", user.ID, err)
+		return ErrDatabaseError
+	}
+
+	s.logAuthEvent(user.ID, "verify_email", "users", user.ID, models.JSON{"email": user.Email})
+	log.Printf("Email verified for user: %s", user.Email)
+	return nil
+}
+
+// ResendVerificationEmail sends a new verification link to the user unless
+// one was sent within the configured cooldown.
+func (s *AuthService) ResendVerificationEmail(userID uint) error {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		log.Printf("Database error while resending verification: %v", err)
+		return ErrDatabaseError
+	}
+
+	if user.VerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	if user.VerificationSentAt != nil && time.Since(*user.VerificationSentAt) < s.verificationCooldown {
+		log.Printf("Verification resend for user %d rejected by cooldown", userID)
+		return ErrVerificationCooldown
+	}
+
+	return s.sendVerificationEmail(user)
+}
+
+// CheckEmailVerified returns ErrEmailNotVerified when the verification policy
+// is enabled and the user has not verified their email yet.
+func (s *AuthService) CheckEmailVerified(userID uint) error {
+	if !s.requireVerifiedEmail {
+		return nil
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return ErrDatabaseError
+	}
+
+	if user.VerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+func (s *AuthService) sendVerificationEmail(user *models.User) error {
+	token, err := s.signVerificationToken(user.ID, user.Email, time.Now().Add(s.verificationTTL))
+	if err != nil {
+		log.Printf("Error generating verification token: %v", err)
+		return err
+	}
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s/verify-email?token=%s\n",
+			user.Name, s.verificationTTL, s.appURL, token),
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		log.Printf("Failed to send verification email to %s: %v", user.Email, err)
+		return errors.New("error sending verification email")
+	}
+
+	if err := s.userRepo.UpdateVerificationSentAt(user.ID, time.Now()); err != nil {
+		log.Printf("Failed to record verification email for user %d: %v", user.ID, err)
+	}
+
+	log.Printf("Verification email sent to: %s", user.Email)
+	return nil
+}
+
+// signVerificationToken encodes the user ID, email and expiry and signs them
+// with HMAC-SHA256 so that verification needs no database lookup of the token.
+func (s *AuthService) signVerificationToken(userID uint, email string, expiresAt time.Time) (string, error) {
+	if len(s.verificationSecret) == 0 {
+		return "", ErrVerificationSecretMissing
+	}
+
+	payload := fmt.Sprintf("%d:%s:%d", userID, email, expiresAt.Unix())
+	mac := hmac.New(sha256.New, s.verificationSecret)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (s *AuthService) parseVerificationToken(token string) (uint, string, error) {
+	if len(s.verificationSecret) == 0 {
+		return 0, "", ErrVerificationSecretMissing
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return 0, "", ErrInvalidVerificationToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return 0, "", ErrInvalidVerificationToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, "", ErrInvalidVerificationToken
+	}
+
+	mac := hmac.New(sha256.New, s.verificationSecret)
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return 0, "", ErrInvalidVerificationToken
+	}
+
+	// payload is "<user id>:<email>:<unix expiry>"
+	fields := strings.Split(string(payload), ":")
+	if len(fields) < 3 {
+		return 0, "", ErrInvalidVerificationToken
+	}
+	userID, err := strconv.ParseUint(fields[0], 10, 32)
+	if err != nil {
+		return 0, "", ErrInvalidVerificationToken
+	}
+	expiry, err := strconv.ParseInt(fields[len(fields)-1], 10, 64)
+	if err != nil {
+		return 0, "", ErrInvalidVerificationToken
+	}
+	if time.Now().After(time.Unix(expiry, 0)) {
+		return 0, "", ErrVerificationTokenExpired
+	}
+
+	email := strings.Join(fields[1:len(fields)-1], ":")
+	return uint(userID), email, nil
+}
+
+// VerificationSecretFromEnv reads the key that signs email verification
+// tokens from EMAIL_VERIFICATION_SECRET. It is kept apart from the JWT keys,
+// so there is no fallback.
+func VerificationSecretFromEnv() ([]byte, error) {
+	secret := os.Getenv("EMAIL_VERIFICATION_SECRET")
+	if secret == "" {
+		return nil, ErrVerificationSecretMissing
+	}
+	return []byte(secret), nil
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultPermissionCacheTTL = time.Minute
+
+// permissionCache keeps the effective permissions of each user in memory so
+// that permission checks do not hit the database on every request. Entries
+// expire after ttl, which bounds how stale another instance's cache can get,
+// and are invalidated explicitly whenever this instance changes grants.
+// Expired entries are swept out at most once per ttl when new ones are
+// stored, so users who stop making requests do not stay in memory.
+type permissionCache struct {
+	ttl       time.Duration
+	mu        sync.RWMutex
+	entries   map[uint]permissionCacheEntry
+	nextSweep time.Time
+
+	// generation is bumped on every invalidation so that a lookup which
+	// started before a grant changed cannot store its stale result after it
+	generation uint64
+}
+
+type permissionCacheEntry struct {
+	permissions map[string]struct{}
+	expiresAt   time.Time
+}
+
+func newPermissionCache(ttl time.Duration) *permissionCache {
+	return &permissionCache{
+		ttl:     ttl,
+		entries: make(map[uint]permissionCacheEntry),
+	}
+}
+
+// get returns the cached permissions of the user. On a miss it returns the
+// current generation, which must be passed to set along with the loaded
+// permissions.
+func (c *permissionCache) get(userID uint) (map[string]struct{}, uint64, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[userID]
+	generation := c.generation
+	c.mu.RUnlock()
+
+	if c.ttl <= 0 || !ok || time.Now().After(entry.expiresAt) {
+		return nil, generation, false
+	}
+	return entry.permissions, generation, true
+}
+
+func (c *permissionCache) set(userID uint, permissions map[string]struct{}, generation uint64) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+
+	now := time.Now()
+	if now.After(c.nextSweep) {
+		for id, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, id)
+			}
+		}
+		c.nextSweep = now.Add(c.ttl)
+	}
+	c.entries[userID] = permissionCacheEntry{
+		permissions: permissions,
+		expiresAt:   now.Add(c.ttl),
+	}
+}
+
+// invalidate drops the cached permissions of a single user.
+func (c *permissionCache) invalidate(userID uint) {
+	c.mu.Lock()
+	delete(c.entries, userID)
+	c.generation++
+	c.mu.Unlock()
+}
+
+// invalidateAll drops every cached entry, used when a role's permissions
+// change and any number of users may be affected.
+func (c *permissionCache) invalidateAll() {
+	c.mu.Lock()
+	c.entries = make(map[uint]permissionCacheEntry)
+	c.generation++
+	c.mu.Unlock()
+}
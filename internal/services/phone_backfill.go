@@ -0,0 +1,88 @@
+package services
+
+import (
+	"bad_boyes/internal/phone"
+	"bad_boyes/internal/repository"
+	"log"
+)
+
+// PhoneBackfillResult counts what a backfill run did.
+type PhoneBackfillResult struct {
+	Scanned int
+	Updated int
+	Invalid int
+}
+
+// PhoneBackfill normalizes the mobile numbers of existing posts and links
+// them to subjects, for rows written before numbers were validated.
+type PhoneBackfill struct {
+	postRepo    *repository.PostRepository
+	subjectRepo *repository.SubjectRepository
+	phones      *phone.Normalizer
+}
+
+func NewPhoneBackfill(postRepo *repository.PostRepository, subjectRepo *repository.SubjectRepository, phones *phone.Normalizer) *PhoneBackfill {
+	return &PhoneBackfill{
+		postRepo:    postRepo,
+		subjectRepo: subjectRepo,
+		phones:      phones,
+	}
+}
+
+// Run walks all posts in batches. Posts whose number cannot be normalized
+// are left without a normalized number and subject, and are logged by ID so
+// they can be fixed by hand. With dryRun nothing is written.
+func (b *PhoneBackfill) Run(batchSize int, dryRun bool) (PhoneBackfillResult, error) {
+	var result PhoneBackfillResult
+	var afterID uint
+
+	for {
+		posts, err := b.postRepo.ListPostPhones(afterID, batchSize)
+		if err != nil {
+			return result, err
+		}
+		if len(posts) == 0 {
+			return result, nil
+		}
+
+		for i := range posts {
+			post := &posts[i]
+			afterID = post.ID
+			result.Scanned++
+
+			oldE164, oldSubjectID := post.MobileNumberE164, post.SubjectID
+			post.MobileNumberE164 = nil
+			if number, err := b.phones.Normalize(post.MobileNumber); err != nil {
+				log.Printf("Post %d has an invalid mobile number: %v", post.ID, err)
+				result.Invalid++
+			} else {
+				post.MobileNumberE164 = &number
+			}
+
+			if dryRun {
+				if !equalString(oldE164, post.MobileNumberE164) {
+					result.Updated++
+				}
+				continue
+			}
+			if err := linkSubject(b.subjectRepo, post); err != nil {
+				return result, err
+			}
+			if equalString(oldE164, post.MobileNumberE164) && equalUint(oldSubjectID, post.SubjectID) {
+				continue
+			}
+			if err := b.postRepo.UpdatePostPhone(post); err != nil {
+				return result, err
+			}
+			result.Updated++
+		}
+	}
+}
+
+func equalString(a, b *string) bool {
+	return a == b || (a != nil && b != nil && *a == *b)
+}
+
+func equalUint(a, b *uint) bool {
+	return a == b || (a != nil && b != nil && *a == *b)
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"bad_boyes/internal/models"
+	"errors"
+	"fmt"
+)
+
+// ErrForbidden is matched by every AuthorizationError, so callers can use
+// errors.Is(err, ErrForbidden) without caring about the details.
+var ErrForbidden = errors.New("forbidden")
+
+// AuthorizationError describes a denied action on a resource.
+type AuthorizationError struct {
+	UserID     uint
+	Permission string
+	ResourceID uint
+}
+
+func (e *AuthorizationError) Error() string {
+	if e.ResourceID != 0 {
+		return fmt.Sprintf("forbidden: missing permission %s for record %d", e.Permission, e.ResourceID)
+	}
+	return fmt.Sprintf("forbidden: missing permission %s", e.Permission)
+}
+
+func (e *AuthorizationError) Unwrap() error {
+	return ErrForbidden
+}
+
+// Policy decides whether a user may perform an action, combining the
+// permissions granted through roles with ownership of the record.
+type Policy struct {
+	roleService *RoleService
+}
+
+func NewPolicy(roleService *RoleService) *Policy {
+	return &Policy{
+		roleService: roleService,
+	}
+}
+
+// Authorize requires the plain "<resource>:<action>" permission.
+func (p *Policy) Authorize(userID uint, resource, action string) error {
+	allowed, err := p.roleService.CheckPermission(userID, resource, action)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return &AuthorizationError{UserID: userID, Permission: resource + ":" + action}
+	}
+	return nil
+}
+
+// AuthorizeOwned allows the action when the user holds
+// "<resource>:<action>:any", or owns the record and holds
+// "<resource>:<action>:own".
+func (p *Policy) AuthorizeOwned(userID uint, resource, action string, ownerID, recordID uint) error {
+	allowed, err := p.roleService.CheckPermission(userID, resource, action+":any")
+	if err != nil {
+		return err
+	}
+	if allowed {
+		return nil
+	}
+
+	if ownerID == userID {
+		allowed, err = p.roleService.CheckPermission(userID, resource, action+":own")
+		if err != nil {
+			return err
+		}
+		if allowed {
+			return nil
+		}
+	}
+
+	return &AuthorizationError{UserID: userID, Permission: resource + ":" + action, ResourceID: recordID}
+}
+
+// AuthorizePostView allows public posts to everyone, and private posts only
+// to their author and users holding posts:read_private.
+func (p *Policy) AuthorizePostView(userID uint, post *models.Post) error {
+	if post.Visibility == "public" || post.UserID == userID {
+		return nil
+	}
+	if err := p.Authorize(userID, "posts", "read_private"); err != nil {
+		var authErr *AuthorizationError
+		if errors.As(err, &authErr) {
+			authErr.ResourceID = post.ID
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"bad_boyes/internal/models"
+	"bad_boyes/internal/repository"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrPreconditionRequired = errors.New("an If-Match header with the post's ETag is required")
+	ErrPreconditionFailed   = errors.New("the post has been changed since it was read")
+)
+
+// PostETag is the entity tag of a post's current version as the user sees
+// it. It changes with every update, so clients send it back in If-Match to
+// make sure they are not overwriting changes they have not seen. Viewers who
+// get differently redacted copies of the same version get different tags.
+func (s *PostService) PostETag(userID uint, post *models.Post) string {
+	return fmt.Sprintf(`"%d-%d-%s"`, post.ID, post.Version, s.redactor.viewer(userID).class(post.UserID))
+}
+
+// ETagMatches reports whether an If-Match or If-None-Match header value
+// lists etag or is "*". If-Match uses the strong comparison of RFC 9110,
+// where weak tags never match; If-None-Match uses the weak one.
+func ETagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch compares the If-Match header of a write with the post's
+// ETag for the user. A missing header is only rejected when
+// POSTS_REQUIRE_IF_MATCH is set.
+func (s *PostService) checkIfMatch(userID uint, post *models.Post, ifMatch string) error {
+	if strings.TrimSpace(ifMatch) == "" {
+		if s.requireIfMatch {
+			return ErrPreconditionRequired
+		}
+		return nil
+	}
+	if !ETagMatches(ifMatch, s.PostETag(userID, post), false) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+// versionConflict reports a write that lost the race against another
+// update of the same post as a failed precondition.
+func versionConflict(err error) error {
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return ErrPreconditionFailed
+	}
+	return err
+}
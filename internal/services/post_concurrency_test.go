@@ -0,0 +1,115 @@
+package services
+
+import (
+	"bad_boyes/internal/models"
+	"errors"
+	"testing"
+)
+
+// newETagPostService returns a post service whose users see posts of others
+// as follows: 1 in full, 2 with authors, 3 with contacts, 4 redacted.
+func newETagPostService(requireIfMatch bool) *PostService {
+	repo := &fakeRoleRepository{
+		roles: map[uint]*models.Role{
+			1: {ID: 1, Name: "author_viewer"},
+			2: {ID: 2, Name: "contact_viewer"},
+		},
+		permissions: map[uint]models.Permission{
+			1: {ID: 1, Resource: "posts", Action: "view_author"},
+			2: {ID: 2, Resource: "posts", Action: "view_contact"},
+		},
+		rolePermissions: map[uint][]uint{1: {1}, 2: {2}},
+		userRoles:       map[uint][]uint{1: {1, 2}, 2: {1}, 3: {2}},
+	}
+	policy := NewPolicy(newTestRoleService(repo, 0))
+	return &PostService{policy: policy, redactor: newRedactor(policy), requireIfMatch: requireIfMatch}
+}
+
+func TestETagMatches(t *testing.T) {
+	const etag = `"12-3-public"`
+
+	for _, tc := range []struct {
+		header string
+		weak   bool
+		want   bool
+	}{
+		{`"12-3-public"`, false, true},
+		{`"12-3-public"`, true, true},
+		{`"12-2-public"`, false, false},
+		{`"12-3-full"`, false, false},
+		{`12-3-public`, false, false},
+		{`"1-1-public", "12-3-public"`, false, true},
+		{`"1-1-public","12-3-public"`, false, true},
+		{`"1-1-public", "12-2-public"`, false, false},
+		{`*`, false, true},
+		{`*`, true, true},
+		{` * `, false, true},
+		{`W/"12-3-public"`, false, false},
+		{`W/"12-3-public"`, true, true},
+		{`"1-1-public", W/"12-3-public"`, true, true},
+		{``, false, false},
+		{``, true, false},
+	} {
+		if got := ETagMatches(tc.header, etag, tc.weak); got != tc.want {
+			t.Errorf("ETagMatches(%q, %q, weak=%v) = %v, want %v", tc.header, etag, tc.weak, got, tc.want)
+		}
+	}
+}
+
+func TestPostETag(t *testing.T) {
+	service := newETagPostService(false)
+	post := &models.Post{ID: 12, UserID: 5, Version: 3}
+
+	for _, tc := range []struct {
+		name   string
+		userID uint
+		want   string
+	}{
+		{"owner", 5, `"12-3-full"`},
+		{"authors and contacts", 1, `"12-3-full"`},
+		{"authors", 2, `"12-3-author"`},
+		{"contacts", 3, `"12-3-contact"`},
+		{"redacted", 4, `"12-3-public"`},
+	} {
+		if got := service.PostETag(tc.userID, post); got != tc.want {
+			t.Errorf("%s: PostETag = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+
+	next := *post
+	next.Version++
+	if service.PostETag(4, post) == service.PostETag(4, &next) {
+		t.Error("PostETag did not change with the version")
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	post := &models.Post{ID: 12, UserID: 5, Version: 3}
+
+	for _, tc := range []struct {
+		name           string
+		requireIfMatch bool
+		userID         uint
+		ifMatch        string
+		err            error
+	}{
+		{"missing header", false, 4, "", nil},
+		{"missing header when required", true, 4, "", ErrPreconditionRequired},
+		{"blank header when required", true, 4, "  ", ErrPreconditionRequired},
+		{"current tag", true, 4, `"12-3-public"`, nil},
+		{"current tag in a list", true, 4, `"12-2-public", "12-3-public"`, nil},
+		{"any version", true, 4, "*", nil},
+		{"stale version", false, 4, `"12-2-public"`, ErrPreconditionFailed},
+		{"weak tag", false, 4, `W/"12-3-public"`, ErrPreconditionFailed},
+		{"tag of another redaction class", false, 4, `"12-3-full"`, ErrPreconditionFailed},
+		{"owner's tag", false, 5, `"12-3-full"`, nil},
+		{"tag of another post", false, 4, `"13-3-public"`, ErrPreconditionFailed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newETagPostService(tc.requireIfMatch)
+			if err := service.checkIfMatch(tc.userID, post, tc.ifMatch); !errors.Is(err, tc.err) {
+				t.Fatalf("checkIfMatch(%q) = %v, want %v", tc.ifMatch, err, tc.err)
+			}
+		})
+	}
+}
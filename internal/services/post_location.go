@@ -0,0 +1,116 @@
+package services
+
+import (
+	"bad_boyes/internal/geo"
+	"bad_boyes/internal/models"
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+var ErrIncompleteCoordinates = errors.New("latitude and longitude must be given together")
+
+const (
+	defaultNearbyRadiusKm = 5
+	defaultNearbyPageSize = 20
+	maxNearbyPageSize     = 100
+	defaultMapLimit       = 200
+	maxMapLimit           = 500
+
+	geocodeTimeout = 5 * time.Second
+)
+
+// NearbyPosts returns the posts the user can see within radiusKm of a
+// point, closest first. A zero radius uses the default of 5 km. Users who
+// only see coarsened coordinates are searched on those as well.
+func (s *PostService) NearbyPosts(userID uint, center geo.Coordinates, radiusKm float64, page, pageSize int) ([]models.PostNearbyResult, int64, error) {
+	if !center.Valid() {
+		return nil, 0, geo.ErrInvalidCoordinates
+	}
+	if err := s.policy.Authorize(userID, "posts", "read"); err != nil {
+		return nil, 0, err
+	}
+	includePrivate := s.policy.Authorize(userID, "posts", "read_private") == nil
+
+	if radiusKm <= 0 {
+		radiusKm = defaultNearbyRadiusKm
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultNearbyPageSize
+	}
+	if pageSize > maxNearbyPageSize {
+		pageSize = maxNearbyPageSize
+	}
+
+	viewer := s.redactor.viewer(userID)
+	results, total, err := s.postRepo.NearbyPosts(center, radiusKm, viewer.locationDecimals(), page, pageSize, &userID, includePrivate)
+	if err != nil {
+		return nil, 0, err
+	}
+	viewer.nearbyResults(results)
+	return results, total, nil
+}
+
+// MapPosts returns the newest posts the user can see inside a map view,
+// along with the total number of posts in it. Like NearbyPosts, users who
+// only see coarsened coordinates are searched on those.
+func (s *PostService) MapPosts(userID uint, minLat, minLng, maxLat, maxLng float64, limit int) ([]models.Post, int64, error) {
+	boxes, err := geo.NewBox(minLat, minLng, maxLat, maxLng)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.policy.Authorize(userID, "posts", "read"); err != nil {
+		return nil, 0, err
+	}
+	includePrivate := s.policy.Authorize(userID, "posts", "read_private") == nil
+
+	if limit < 1 {
+		limit = defaultMapLimit
+	}
+	if limit > maxMapLimit {
+		limit = maxMapLimit
+	}
+
+	viewer := s.redactor.viewer(userID)
+	posts, total, err := s.postRepo.PostsInBoxes(boxes, viewer.locationDecimals(), limit, &userID, includePrivate)
+	if err != nil {
+		return nil, 0, err
+	}
+	viewer.posts(posts)
+	return posts, total, nil
+}
+
+// locate sets a post's coordinates from the request, or geocodes its
+// address when none were given and a geocoder is configured. A failed
+// lookup leaves the post without coordinates rather than rejecting it.
+func (s *PostService) locate(post *models.Post, latitude, longitude *float64, geocodeAddress bool) error {
+	if (latitude == nil) != (longitude == nil) {
+		return ErrIncompleteCoordinates
+	}
+	if latitude != nil {
+		coords := geo.Coordinates{Latitude: *latitude, Longitude: *longitude}
+		if !coords.Valid() {
+			return geo.ErrInvalidCoordinates
+		}
+		post.Latitude, post.Longitude = &coords.Latitude, &coords.Longitude
+		return nil
+	}
+	if !geocodeAddress || s.geocoder == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), geocodeTimeout)
+	defer cancel()
+	coords, err := s.geocoder.Geocode(ctx, post.Address)
+	if err != nil {
+		log.Printf("Failed to geocode address of post %d: %v", post.ID, err)
+		post.Latitude, post.Longitude = nil, nil
+		return nil
+	}
+	post.Latitude, post.Longitude = &coords.Latitude, &coords.Longitude
+	return nil
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"bad_boyes/internal/models"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var ErrInvalidPatch = errors.New("invalid merge patch")
+
+// documentValidator checks merged documents against the same binding tags
+// gin uses for request bodies.
+var documentValidator = func() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+	return v
+}()
+
+// PatchPost applies a JSON merge patch (RFC 7386) to a post. The patch is
+// merged into the post's PostDocument and the result is validated as a
+// whole, so members left out of the patch keep their values and null
+// removes optional ones. Only the fields the patch actually changes are
+// saved to the history and the audit log. ifMatch is the request's If-Match
+// header, checked against the post's ETag.
+func (s *PostService) PatchPost(userID uint, postID uint, patch []byte, ifMatch string) (*models.Post, error) {
+	log.Printf("Starting post patch for post ID: %d by user ID: %d", postID, userID)
+
+	post, err := s.postRepo.GetPostByID(postID)
+	if err != nil {
+		log.Printf("Failed to fetch post: %v", err)
+		return nil, err
+	}
+
+	if err := s.policy.AuthorizeOwned(userID, "posts", "update", post.UserID, post.ID); err != nil {
+		log.Printf("Unauthorized patch attempt on post %d (owner %d) by user %d: %v", post.ID, post.UserID, userID, err)
+		return nil, err
+	}
+	if err := s.checkIfMatch(userID, post, ifMatch); err != nil {
+		return nil, err
+	}
+
+	doc, err := mergePostDocument(postDocument(post), patch)
+	if err != nil {
+		return nil, err
+	}
+
+	oldValues := postValues(post)
+	tags, replaceTags, err := s.applyDocument(post, doc, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveUpdate(userID, "update", post, oldValues, tags, replaceTags); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Post patch completed successfully for post ID: %d", postID)
+	return s.redactedPost(userID, post.ID)
+}
+
+// applyDocument sets the editable fields of a post from a validated
+// document, returning the tags to save when they changed. With geocode, a
+// new address without new coordinates is geocoded.
+func (s *PostService) applyDocument(post *models.Post, doc models.PostDocument, geocode bool) ([]models.Tag, bool, error) {
+	current := postDocument(post)
+	post.Title = doc.Title
+	post.Description = doc.Description
+	post.ContactName = doc.ContactName
+	post.IncidentDate = *doc.IncidentDate
+	post.IsAnonymous = *doc.IsAnonymous
+	post.Visibility = doc.Visibility
+	post.AllowComments = *doc.AllowComments
+
+	if doc.MobileNumber != current.MobileNumber {
+		mobileE164, err := s.normalizeMobileNumber(doc.MobileNumber)
+		if err != nil {
+			return nil, false, err
+		}
+		post.MobileNumber = doc.MobileNumber
+		post.MobileNumberE164 = &mobileE164
+	}
+	if !reflect.DeepEqual(doc.CategoryID, current.CategoryID) {
+		if doc.CategoryID != nil {
+			if err := s.checkCategory(*doc.CategoryID); err != nil {
+				return nil, false, err
+			}
+		}
+		post.CategoryID = doc.CategoryID
+		post.Category = nil
+	}
+
+	addressChanged := doc.Address != current.Address
+	post.Address = doc.Address
+	if !reflect.DeepEqual(doc.Latitude, current.Latitude) || !reflect.DeepEqual(doc.Longitude, current.Longitude) {
+		if doc.Latitude == nil && doc.Longitude == nil {
+			post.Latitude, post.Longitude = nil, nil
+		} else if err := s.locate(post, doc.Latitude, doc.Longitude, false); err != nil {
+			return nil, false, err
+		}
+	} else if addressChanged && geocode {
+		// A new address is geocoded unless the patch also sets coordinates
+		if err := s.locate(post, nil, nil, true); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if reflect.DeepEqual(doc.Tags, current.Tags) {
+		return nil, false, nil
+	}
+	tags, err := s.resolveTags(doc.Tags)
+	if err != nil {
+		return nil, false, err
+	}
+	return tags, true, nil
+}
+
+// postDocument returns the editable fields of a post.
+func postDocument(post *models.Post) models.PostDocument {
+	incidentDate := post.IncidentDate
+	isAnonymous := post.IsAnonymous
+	allowComments := post.AllowComments
+	return models.PostDocument{
+		Title:         post.Title,
+		Description:   post.Description,
+		Address:       post.Address,
+		ContactName:   post.ContactName,
+		MobileNumber:  post.MobileNumber,
+		IncidentDate:  &incidentDate,
+		IsAnonymous:   &isAnonymous,
+		Visibility:    post.Visibility,
+		AllowComments: &allowComments,
+		CategoryID:    post.CategoryID,
+		Tags:          tagNames(post.Tags),
+		Latitude:      post.Latitude,
+		Longitude:     post.Longitude,
+	}
+}
+
+// mergePostDocument applies a merge patch to a document and validates the
+// result. Members that are not part of PostDocument are rejected.
+func mergePostDocument(current models.PostDocument, patch []byte) (models.PostDocument, error) {
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return models.PostDocument{}, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	if _, ok := patchValue.(map[string]interface{}); !ok {
+		return models.PostDocument{}, fmt.Errorf("%w: patch must be a JSON object", ErrInvalidPatch)
+	}
+
+	encoded, err := json.Marshal(current)
+	if err != nil {
+		return models.PostDocument{}, err
+	}
+	var target interface{}
+	if err := json.Unmarshal(encoded, &target); err != nil {
+		return models.PostDocument{}, err
+	}
+	merged, err := json.Marshal(mergePatch(target, patchValue))
+	if err != nil {
+		return models.PostDocument{}, err
+	}
+
+	var doc models.PostDocument
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&doc); err != nil {
+		return models.PostDocument{}, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	if err := documentValidator.Struct(&doc); err != nil {
+		return models.PostDocument{}, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	if doc.Tags == nil {
+		doc.Tags = []string{}
+	}
+	return doc, nil
+}
+
+// mergePatch implements the MergePatch function of RFC 7386: objects are
+// merged member by member, null removes a member and any other value
+// replaces the target.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+	return targetObject
+}
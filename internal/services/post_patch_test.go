@@ -0,0 +1,113 @@
+package services
+
+import (
+	"bad_boyes/internal/models"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func decodeJSON(t *testing.T, data string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+	return value
+}
+
+// TestMergePatch runs the examples of RFC 7386, Appendix A.
+func TestMergePatch(t *testing.T) {
+	for _, tc := range []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	} {
+		got := mergePatch(decodeJSON(t, tc.target), decodeJSON(t, tc.patch))
+		if want := decodeJSON(t, tc.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("mergePatch(%s, %s) = %v, want %s", tc.target, tc.patch, got, tc.want)
+		}
+	}
+}
+
+func TestMergePostDocument(t *testing.T) {
+	incidentDate := models.Date(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	isAnonymous, allowComments := false, true
+	categoryID := uint(3)
+	latitude, longitude := 51.5, -0.12
+	current := models.PostDocument{
+		Title:         "Title",
+		Description:   "Description",
+		Address:       "1 High Street, London",
+		ContactName:   "Jane Doe",
+		MobileNumber:  "+447700900123",
+		IncidentDate:  &incidentDate,
+		IsAnonymous:   &isAnonymous,
+		Visibility:    "public",
+		AllowComments: &allowComments,
+		CategoryID:    &categoryID,
+		Tags:          []string{"scam"},
+		Latitude:      &latitude,
+		Longitude:     &longitude,
+	}
+
+	for _, tc := range []struct {
+		name  string
+		patch string
+		check func(doc models.PostDocument) bool
+		err   error
+	}{
+		{"empty patch keeps everything", `{}`, func(doc models.PostDocument) bool {
+			return reflect.DeepEqual(doc, current)
+		}, nil},
+		{"replaces a member", `{"title":"New title"}`, func(doc models.PostDocument) bool {
+			return doc.Title == "New title" && doc.Description == current.Description
+		}, nil},
+		{"null removes optional members", `{"category_id":null,"latitude":null,"longitude":null}`, func(doc models.PostDocument) bool {
+			return doc.CategoryID == nil && doc.Latitude == nil && doc.Longitude == nil && doc.Title == current.Title
+		}, nil},
+		{"null clears the tags", `{"tags":null}`, func(doc models.PostDocument) bool {
+			return doc.Tags != nil && len(doc.Tags) == 0
+		}, nil},
+		{"arrays are replaced", `{"tags":["fraud","phone"]}`, func(doc models.PostDocument) bool {
+			return reflect.DeepEqual(doc.Tags, []string{"fraud", "phone"})
+		}, nil},
+		{"false is a value, not a removal", `{"allow_comments":false}`, func(doc models.PostDocument) bool {
+			return doc.AllowComments != nil && !*doc.AllowComments
+		}, nil},
+		{"null on a required member", `{"title":null}`, nil, ErrInvalidPatch},
+		{"null on a required pointer", `{"is_anonymous":null}`, nil, ErrInvalidPatch},
+		{"invalid value", `{"visibility":"friends"}`, nil, ErrInvalidPatch},
+		{"unknown member", `{"user_id":1}`, nil, ErrInvalidPatch},
+		{"wrong type", `{"title":1}`, nil, ErrInvalidPatch},
+		{"patch is not an object", `["title"]`, nil, ErrInvalidPatch},
+		{"patch is null", `null`, nil, ErrInvalidPatch},
+		{"malformed JSON", `{"title":`, nil, ErrInvalidPatch},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := mergePostDocument(current, []byte(tc.patch))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("mergePostDocument(%s) error = %v, want %v", tc.patch, err, tc.err)
+			}
+			if err == nil && !tc.check(doc) {
+				t.Fatalf("mergePostDocument(%s) = %+v", tc.patch, doc)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"bad_boyes/internal/models"
+	"errors"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// GetRevisionDiff compares two revisions of a post field by field. Without
+// against, a revision is compared with the one before it, which shows what
+// the update that created it changed. Revision 0 is the empty post.
+func (s *PostService) GetRevisionDiff(userID, postID, revision, against uint) (*models.RevisionDiff, error) {
+	post, err := s.postRepo.GetPostByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.AuthorizeOwned(userID, "posts", "history", post.UserID, post.ID); err != nil {
+		return nil, err
+	}
+
+	if against == 0 && revision > 0 {
+		against = revision - 1
+	}
+	to, err := s.revision(post, revision)
+	if err != nil {
+		return nil, err
+	}
+	from := &models.PostHistory{}
+	if against > 0 {
+		if from, err = s.revision(post, against); err != nil {
+			return nil, err
+		}
+	}
+
+	entries := []models.PostHistory{*from, *to}
+	s.redactor.viewer(userID).history(entries)
+	fromValues := models.JSON{}
+	if against > 0 {
+		fromValues = revisionValues(&entries[0])
+	}
+	oldChanged, newChanged, fields := changedValues(fromValues, revisionValues(&entries[1]))
+
+	diff := &models.RevisionDiff{
+		PostID:       post.ID,
+		FromRevision: against,
+		ToRevision:   revision,
+		Changes:      make([]models.FieldChange, len(fields)),
+	}
+	for i, field := range fields {
+		diff.Changes[i] = models.FieldChange{Field: field, From: oldChanged[field], To: newChanged[field]}
+	}
+	return diff, nil
+}
+
+// RevertPost restores the content of an earlier revision as a new revision
+// of the post; the history up to it is kept. Status and moderation state
+// are not part of revisions and stay as they are. ifMatch is the request's
+// If-Match header, checked against the post's ETag.
+func (s *PostService) RevertPost(userID, postID, revision uint, ifMatch string) (*models.Post, error) {
+	log.Printf("Reverting post %d to revision %d by user ID: %d", postID, revision, userID)
+
+	post, err := s.postRepo.GetPostByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.AuthorizeOwned(userID, "posts", "update", post.UserID, post.ID); err != nil {
+		log.Printf("Unauthorized revert attempt on post %d (owner %d) by user %d: %v", post.ID, post.UserID, userID, err)
+		return nil, err
+	}
+	if err := s.checkIfMatch(userID, post, ifMatch); err != nil {
+		return nil, err
+	}
+
+	target, err := s.revision(post, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	oldValues := postValues(post)
+	tags, replaceTags, err := s.applyDocument(post, revisionDocument(target), false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveUpdate(userID, "revert", post, oldValues, tags, replaceTags); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Post %d reverted to revision %d", postID, revision)
+	return s.redactedPost(userID, post.ID)
+}
+
+// revision returns a revision of a post, which is either its current state
+// or a history entry.
+func (s *PostService) revision(post *models.Post, revision uint) (*models.PostHistory, error) {
+	if revision == 0 || revision > post.Version {
+		return nil, ErrRevisionNotFound
+	}
+	if revision == post.Version {
+		snapshot := post.Snapshot()
+		return &snapshot, nil
+	}
+
+	entry, err := s.postRepo.GetPostRevision(post.ID, revision)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRevisionNotFound
+	}
+	return entry, err
+}
+
+// revisionDocument returns the editable fields of a revision.
+func revisionDocument(revision *models.PostHistory) models.PostDocument {
+	incidentDate := revision.IncidentDate
+	isAnonymous := revision.IsAnonymous
+	allowComments := revision.AllowComments
+	tags := revision.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return models.PostDocument{
+		Title:         revision.Title,
+		Description:   revision.Description,
+		Address:       revision.Address,
+		ContactName:   revision.ContactName,
+		MobileNumber:  revision.MobileNumber,
+		IncidentDate:  &incidentDate,
+		IsAnonymous:   &isAnonymous,
+		Visibility:    revision.Visibility,
+		AllowComments: &allowComments,
+		CategoryID:    revision.CategoryID,
+		Tags:          tags,
+		Latitude:      revision.Latitude,
+		Longitude:     revision.Longitude,
+	}
+}
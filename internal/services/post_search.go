@@ -0,0 +1,146 @@
+package services
+
+import (
+	"bad_boyes/internal/models"
+	"errors"
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrInvalidSearchQuery = errors.New("search query must be between 2 and 200 characters")
+
+const (
+	defaultSearchPageSize = 10
+	maxSearchPageSize     = 50
+
+	// snippetRadius is the number of characters kept on each side of the
+	// first match when a long field is cut down to a snippet.
+	snippetRadius = 60
+
+	highlightOpen  = "<mark>"
+	highlightClose = "</mark>"
+)
+
+// SearchPosts returns posts matching a full-text query on title and
+// description or, for users who may see contact details, whole words of
+// address and contact name, most relevant first, with highlighted snippets
+// of the fields that matched.
+func (s *PostService) SearchPosts(userID uint, query string, page, pageSize int) ([]models.PostSearchResult, int64, error) {
+	query = strings.TrimSpace(query)
+	if length := utf8.RuneCountInString(query); length < 2 || length > 200 {
+		return nil, 0, ErrInvalidSearchQuery
+	}
+
+	if err := s.policy.Authorize(userID, "posts", "read"); err != nil {
+		return nil, 0, err
+	}
+	includePrivate := s.policy.Authorize(userID, "posts", "read_private") == nil
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultSearchPageSize
+	}
+	if pageSize > maxSearchPageSize {
+		pageSize = maxSearchPageSize
+	}
+
+	// Address and contact name are encrypted and matched by word through
+	// their blind indexes. Matching them for anyone else would confirm
+	// guesses at the contact details that are masked for them.
+	viewer := s.redactor.viewer(userID)
+	terms := models.SearchWords(query)
+	var tokens []string
+	if viewer.seeContacts {
+		var err error
+		if tokens, err = models.SearchTokens(query); err != nil {
+			return nil, 0, err
+		}
+	}
+	results, total, err := s.postRepo.SearchPosts(query, tokens, page, pageSize, &userID, includePrivate)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range results {
+		post := &results[i].Post
+		results[i].Highlights = highlightFields(terms, map[string]string{
+			"title":        post.Title,
+			"description":  post.Description,
+			"address":      post.Address,
+			"contact_name": post.ContactName,
+		})
+	}
+	viewer.searchResults(results)
+	return results, total, nil
+}
+
+// highlightFields returns an escaped snippet for every field that contains
+// at least one term, with the terms wrapped in <mark> tags.
+func highlightFields(terms []string, fields map[string]string) map[string]string {
+	highlights := make(map[string]string)
+	for name, value := range fields {
+		if snippet, ok := highlight(value, terms); ok {
+			highlights[name] = snippet
+		}
+	}
+	return highlights
+}
+
+func highlight(text string, terms []string) (string, bool) {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		// Lower-casing changed the length; fall back to exact matching
+		lower = runes
+	}
+
+	// Mark every rune covered by a term
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		needle := []rune(term)
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if string(lower[i:i+len(needle)]) != term {
+				continue
+			}
+			for j := i; j < i+len(needle); j++ {
+				marked[j] = true
+			}
+			if first == -1 || i < first {
+				first = i
+			}
+		}
+	}
+	if first == -1 {
+		return "", false
+	}
+
+	start, end := 0, len(runes)
+	if first > snippetRadius {
+		start = first - snippetRadius
+	}
+	if end-first > 2*snippetRadius {
+		end = first + 2*snippetRadius
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; i++ {
+		if marked[i] && (i == start || !marked[i-1]) {
+			b.WriteString(highlightOpen)
+		}
+		b.WriteString(html.EscapeString(string(runes[i])))
+		if marked[i] && (i == end-1 || !marked[i+1]) {
+			b.WriteString(highlightClose)
+		}
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
@@ -1,31 +1,104 @@
 package services
 
 import (
+	"bad_boyes/internal/config"
+	"bad_boyes/internal/fieldcrypt"
+	"bad_boyes/internal/geo"
 	"bad_boyes/internal/models"
+	"bad_boyes/internal/pagination"
+	"bad_boyes/internal/phone"
 	"bad_boyes/internal/repository"
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"sort"
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// FilterError reports an invalid list filter parameter.
+type FilterError struct {
+	Field   string
+	Message string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("invalid filter %s: %s", e.Field, e.Message)
+}
+
+var ErrRestoreWindowExpired = errors.New("the restore period for this post has expired")
+
+const (
+	defaultRestoreWindow  = 72 * time.Hour
+	defaultTrashRetention = 30 * 24 * time.Hour
+	purgeBatchSize        = 500
 )
 
 type PostService struct {
-	postRepo  *repository.PostRepository
-	auditRepo *repository.AuditRepository
+	postRepo     *repository.PostRepository
+	taxonomyRepo *repository.TaxonomyRepository
+	subjectRepo  *repository.SubjectRepository
+	auditRepo    *repository.AuditRepository
+	policy       *Policy
+	geocoder     geo.Geocoder
+	phones       *phone.Normalizer
+	redactor     *redactor
+
+	// restoreWindow is how long owners can restore their deleted posts;
+	// trashRetention is how long deleted posts are kept before purging.
+	restoreWindow  time.Duration
+	trashRetention time.Duration
+	purgeHooks     []func(postIDs []uint) error
+
+	// requireIfMatch rejects updates and deletes that do not say which
+	// version of the post they were based on.
+	requireIfMatch bool
 }
 
-func NewPostService(postRepo *repository.PostRepository, auditRepo *repository.AuditRepository) *PostService {
+func NewPostService(postRepo *repository.PostRepository, taxonomyRepo *repository.TaxonomyRepository, subjectRepo *repository.SubjectRepository, auditRepo *repository.AuditRepository, policy *Policy, geocoder geo.Geocoder, phones *phone.Normalizer) *PostService {
 	return &PostService{
-		postRepo:  postRepo,
-		auditRepo: auditRepo,
+		postRepo:       postRepo,
+		taxonomyRepo:   taxonomyRepo,
+		subjectRepo:    subjectRepo,
+		auditRepo:      auditRepo,
+		policy:         policy,
+		geocoder:       geocoder,
+		phones:         phones,
+		redactor:       newRedactor(policy),
+		restoreWindow:  config.GetDuration("POST_RESTORE_WINDOW", defaultRestoreWindow),
+		trashRetention: config.GetDuration("POST_TRASH_RETENTION", defaultTrashRetention),
+		requireIfMatch: config.GetBool("POSTS_REQUIRE_IF_MATCH", false),
 	}
 }
 
 func (s *PostService) CreatePost(userID uint, req models.CreatePostRequest) (*models.Post, error) {
 	log.Printf("Starting post creation for user ID: %d", userID)
-	log.Printf("Request data: %+v", req)
+
+	if err := s.policy.Authorize(userID, "posts", "create"); err != nil {
+		return nil, err
+	}
+
+	if req.CategoryID != nil {
+		if err := s.checkCategory(*req.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+	tags, err := s.resolveTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	mobileE164, err := s.normalizeMobileNumber(req.MobileNumber)
+	if err != nil {
+		return nil, err
+	}
 
 	post := &models.Post{
 		UserID:        userID,
+		CategoryID:    req.CategoryID,
+		Tags:          tags,
 		Title:         req.Title,
 		Description:   req.Description,
 		Address:       req.Address,
@@ -37,7 +110,14 @@ func (s *PostService) CreatePost(userID uint, req models.CreatePostRequest) (*mo
 		AllowComments: req.AllowComments,
 		Status:        "active",
 	}
-	log.Printf("Created post object: %+v", post)
+	post.MobileNumberE164 = &mobileE164
+	if err := s.locate(post, req.Latitude, req.Longitude, true); err != nil {
+		return nil, err
+	}
+	if err := linkSubject(s.subjectRepo, post); err != nil {
+		log.Printf("Failed to link post to subject: %v", err)
+		return nil, err
+	}
 
 	log.Printf("Attempting to save post to database")
 	if err := s.postRepo.CreatePost(post); err != nil {
@@ -53,7 +133,7 @@ func (s *PostService) CreatePost(userID uint, req models.CreatePostRequest) (*mo
 		Action:    "create",
 		TableName: "posts",
 		RecordID:  post.ID,
-		NewValues: models.JSON{
+		NewValues: auditValues(models.JSON{
 			"title":          post.Title,
 			"description":    post.Description,
 			"address":        post.Address,
@@ -64,9 +144,12 @@ func (s *PostService) CreatePost(userID uint, req models.CreatePostRequest) (*mo
 			"visibility":     post.Visibility,
 			"allow_comments": post.AllowComments,
 			"status":         post.Status,
-		},
+			"category_id":    post.CategoryID,
+			"tags":           tagNames(post.Tags),
+			"latitude":       post.Latitude,
+			"longitude":      post.Longitude,
+		}),
 	}
-	log.Printf("Audit log object created: %+v", auditLog)
 
 	log.Printf("Attempting to save audit log")
 	if err := s.auditRepo.CreateLog(auditLog); err != nil {
@@ -79,39 +162,44 @@ func (s *PostService) CreatePost(userID uint, req models.CreatePostRequest) (*mo
 	return post, nil
 }
 
-func (s *PostService) GetPost(id uint) (*models.Post, error) {
-	return s.postRepo.GetPostByID(id)
+func (s *PostService) GetPost(userID uint, id uint) (*models.Post, error) {
+	if err := s.policy.Authorize(userID, "posts", "read"); err != nil {
+		return nil, err
+	}
+
+	post, err := s.postRepo.GetPostByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.policy.AuthorizePostView(userID, post); err != nil {
+		return nil, err
+	}
+	s.redactor.viewer(userID).post(post)
+	return post, nil
 }
 
-func (s *PostService) UpdatePost(userID uint, postID uint, req models.UpdatePostRequest) (*models.Post, error) {
+// UpdatePost changes the fields set in the request. ifMatch is the request's
+// If-Match header, checked against the post's ETag.
+func (s *PostService) UpdatePost(userID uint, postID uint, req models.UpdatePostRequest, ifMatch string) (*models.Post, error) {
 	log.Printf("Starting post update for post ID: %d by user ID: %d", postID, userID)
-	log.Printf("Update request data: %+v", req)
 
 	post, err := s.postRepo.GetPostByID(postID)
 	if err != nil {
 		log.Printf("Failed to fetch post: %v", err)
 		return nil, err
 	}
-	log.Printf("Found existing post: %+v", post)
 
-	if post.UserID != userID {
-		log.Printf("Unauthorized update attempt: post belongs to user %d, but user %d is trying to update", post.UserID, userID)
-		return nil, errors.New("unauthorized")
+	if err := s.policy.AuthorizeOwned(userID, "posts", "update", post.UserID, post.ID); err != nil {
+		log.Printf("Unauthorized update attempt on post %d (owner %d) by user %d: %v", post.ID, post.UserID, userID, err)
+		return nil, err
+	}
+	if err := s.checkIfMatch(userID, post, ifMatch); err != nil {
+		return nil, err
 	}
 
 	// Store old values for audit
-	oldValues := models.JSON{
-		"title":          post.Title,
-		"description":    post.Description,
-		"address":        post.Address,
-		"contact_name":   post.ContactName,
-		"mobile_number":  post.MobileNumber,
-		"incident_date":  time.Time(post.IncidentDate).Format("2006-01-02"),
-		"is_anonymous":   post.IsAnonymous,
-		"visibility":     post.Visibility,
-		"allow_comments": post.AllowComments,
-	}
-	log.Printf("Stored old values for audit: %+v", oldValues)
+	oldValues := postValues(post)
 
 	// Update fields if provided
 	if req.Title != "" {
@@ -122,90 +210,252 @@ func (s *PostService) UpdatePost(userID uint, postID uint, req models.UpdatePost
 		log.Printf("Updating description")
 		post.Description = req.Description
 	}
+	addressChanged := req.Address != "" && req.Address != post.Address
 	if req.Address != "" {
-		log.Printf("Updating address from '%s' to '%s'", post.Address, req.Address)
+		log.Printf("Updating address")
 		post.Address = req.Address
 	}
 	if req.ContactName != "" {
-		log.Printf("Updating contact name from '%s' to '%s'", post.ContactName, req.ContactName)
+		log.Printf("Updating contact name")
 		post.ContactName = req.ContactName
 	}
 	if req.MobileNumber != "" {
-		log.Printf("Updating mobile number from '%s' to '%s'", post.MobileNumber, req.MobileNumber)
+		mobileE164, err := s.normalizeMobileNumber(req.MobileNumber)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Updating mobile number")
 		post.MobileNumber = req.MobileNumber
+		post.MobileNumberE164 = &mobileE164
 	}
 	if req.IncidentDate != (models.Date{}) {
 		log.Printf("Updating incident date")
 		post.IncidentDate = req.IncidentDate
 	}
-	post.IsAnonymous = req.IsAnonymous
+	if req.IsAnonymous != nil {
+		post.IsAnonymous = *req.IsAnonymous
+	}
 	if req.Visibility != "" {
 		log.Printf("Updating visibility from '%s' to '%s'", post.Visibility, req.Visibility)
 		post.Visibility = req.Visibility
 	}
-	post.AllowComments = req.AllowComments
+	if req.AllowComments != nil {
+		post.AllowComments = *req.AllowComments
+	}
+	if req.CategoryID != nil {
+		if *req.CategoryID == 0 {
+			post.CategoryID = nil
+		} else {
+			if err := s.checkCategory(*req.CategoryID); err != nil {
+				return nil, err
+			}
+			post.CategoryID = req.CategoryID
+		}
+		post.Category = nil
+	}
+	if err := s.locate(post, req.Latitude, req.Longitude, addressChanged); err != nil {
+		return nil, err
+	}
 
-	log.Printf("Attempting to save updated post")
-	if err := s.postRepo.UpdatePost(post); err != nil {
-		log.Printf("Failed to update post: %v", err)
+	var tags []models.Tag
+	if req.Tags != nil {
+		if tags, err = s.resolveTags(req.Tags); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.saveUpdate(userID, "update", post, oldValues, tags, req.Tags != nil); err != nil {
 		return nil, err
 	}
+
+	log.Printf("Post update completed successfully for post ID: %d", postID)
+	return s.redactedPost(userID, post.ID)
+}
+
+// saveUpdate stores the changes made to a post as a new revision. Only the
+// fields that differ from oldValues are recorded in the post's history and
+// under the given action in the audit log, and nothing is written when no
+// field changed. Tags are replaced when replaceTags is set.
+func (s *PostService) saveUpdate(userID uint, action string, post *models.Post, oldValues models.JSON, tags []models.Tag, replaceTags bool) error {
+	if replaceTags {
+		post.Tags = tags
+	}
+	oldChanged, newChanged, changedFields := changedValues(oldValues, postValues(post))
+	if len(changedFields) == 0 {
+		log.Printf("Post %d is unchanged, nothing to save", post.ID)
+		return nil
+	}
+
+	if err := linkSubject(s.subjectRepo, post); err != nil {
+		log.Printf("Failed to link post to subject: %v", err)
+		return err
+	}
+
+	log.Printf("Attempting to save updated post, changed fields: %v", changedFields)
+	if err := s.postRepo.UpdatePost(post, userID, changedFields, replaceTags); err != nil {
+		log.Printf("Failed to update post: %v", err)
+		return versionConflict(err)
+	}
 	log.Printf("Post updated successfully")
 
 	// Create audit log
-	log.Printf("Creating audit log for post update")
+	log.Printf("Creating audit log for post %s", action)
 	auditLog := &models.AuditLog{
 		UserID:    &userID,
-		Action:    "update",
+		Action:    action,
 		TableName: "posts",
 		RecordID:  post.ID,
-		OldValues: oldValues,
-		NewValues: models.JSON{
-			"title":          post.Title,
-			"description":    post.Description,
-			"address":        post.Address,
-			"contact_name":   post.ContactName,
-			"mobile_number":  post.MobileNumber,
-			"incident_date":  time.Time(post.IncidentDate).Format("2006-01-02"),
-			"is_anonymous":   post.IsAnonymous,
-			"visibility":     post.Visibility,
-			"allow_comments": post.AllowComments,
-		},
+		OldValues: auditValues(oldChanged),
+		NewValues: auditValues(newChanged),
 	}
-	log.Printf("Audit log object created: %+v", auditLog)
 
 	log.Printf("Attempting to save audit log")
 	if err := s.auditRepo.CreateLog(auditLog); err != nil {
 		log.Printf("Failed to create audit log: %v", err)
-		return nil, err
+		return err
 	}
 	log.Printf("Audit log saved successfully")
+	return nil
+}
 
-	log.Printf("Post update completed successfully for post ID: %d", postID)
-	return post, nil
+// postValues is the editable state of a post as recorded in the audit log.
+func postValues(post *models.Post) models.JSON {
+	snapshot := post.Snapshot()
+	return revisionValues(&snapshot)
+}
+
+// revisionValues is the editable state of a post revision, keyed like the
+// request fields.
+func revisionValues(revision *models.PostHistory) models.JSON {
+	return models.JSON{
+		"title":          revision.Title,
+		"description":    revision.Description,
+		"address":        revision.Address,
+		"contact_name":   revision.ContactName,
+		"mobile_number":  revision.MobileNumber,
+		"incident_date":  time.Time(revision.IncidentDate).Format("2006-01-02"),
+		"is_anonymous":   revision.IsAnonymous,
+		"visibility":     revision.Visibility,
+		"allow_comments": revision.AllowComments,
+		"category_id":    revision.CategoryID,
+		"tags":           revision.Tags,
+		"latitude":       revision.Latitude,
+		"longitude":      revision.Longitude,
+	}
 }
 
-func (s *PostService) DeletePost(userID uint, postID uint) error {
+// auditContactFields are encrypted at rest, so audit entries only record that
+// they changed and never their values.
+var auditContactFields = []string{"address", "contact_name", "mobile_number"}
+
+const auditRedacted = "[redacted]"
+
+// auditValues replaces the contact details in audit values with a marker.
+func auditValues(values models.JSON) models.JSON {
+	for _, field := range auditContactFields {
+		if _, ok := values[field]; ok {
+			values[field] = auditRedacted
+		}
+	}
+	return values
+}
+
+// changedValues keeps the entries of two value sets that differ, comparing
+// them by their JSON form, and returns the changed keys in order.
+func changedValues(oldValues, newValues models.JSON) (models.JSON, models.JSON, []string) {
+	oldChanged, newChanged := models.JSON{}, models.JSON{}
+	var fields []string
+	for key, value := range newValues {
+		before, _ := json.Marshal(oldValues[key])
+		after, _ := json.Marshal(value)
+		if bytes.Equal(before, after) {
+			continue
+		}
+		oldChanged[key] = oldValues[key]
+		newChanged[key] = value
+		fields = append(fields, key)
+	}
+	sort.Strings(fields)
+	return oldChanged, newChanged, fields
+}
+
+// normalizeMobileNumber returns the E.164 form of a post's mobile number,
+// or an error wrapping phone.ErrInvalidNumber that says why it is invalid.
+func (s *PostService) normalizeMobileNumber(raw string) (string, error) {
+	number, err := s.phones.Normalize(raw)
+	if err != nil {
+		return "", fmt.Errorf("mobile_number: %w", err)
+	}
+	return number, nil
+}
+
+// redactedPost reloads a post after a change and shapes it for the user.
+func (s *PostService) redactedPost(userID, postID uint) (*models.Post, error) {
 	post, err := s.postRepo.GetPostByID(postID)
 	if err != nil {
+		return nil, err
+	}
+	s.redactor.viewer(userID).post(post)
+	return post, nil
+}
+
+func (s *PostService) checkCategory(categoryID uint) error {
+	if _, err := s.taxonomyRepo.GetCategory(categoryID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCategoryNotFound
+		}
 		return err
 	}
+	return nil
+}
 
-	if post.UserID != userID {
-		return errors.New("unauthorized")
+// resolveTags normalizes tag names and returns the matching tags, creating
+// new ones as needed.
+func (s *PostService) resolveTags(names []string) ([]models.Tag, error) {
+	names, err := normalizeTags(names)
+	if err != nil {
+		return nil, err
 	}
+	return s.taxonomyRepo.FindOrCreateTags(names)
+}
+
+// tagNames returns the names of tags in alphabetical order.
+func tagNames(tags []models.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	sort.Strings(names)
+	return names
+}
 
-	if err := s.postRepo.DeletePost(postID); err != nil {
+// DeletePost moves a post to the trash. ifMatch is the request's If-Match
+// header, checked against the post's ETag.
+func (s *PostService) DeletePost(userID uint, postID uint, ifMatch string) error {
+	post, err := s.postRepo.GetPostByID(postID)
+	if err != nil {
 		return err
 	}
 
+	if err := s.policy.AuthorizeOwned(userID, "posts", "delete", post.UserID, post.ID); err != nil {
+		log.Printf("Unauthorized delete attempt on post %d (owner %d) by user %d: %v", post.ID, post.UserID, userID, err)
+		return err
+	}
+	if err := s.checkIfMatch(userID, post, ifMatch); err != nil {
+		return err
+	}
+
+	if err := s.postRepo.DeletePost(postID, post.Version); err != nil {
+		return versionConflict(err)
+	}
+
 	// Create audit log
 	auditLog := &models.AuditLog{
 		UserID:    &userID,
 		Action:    "delete",
 		TableName: "posts",
 		RecordID:  postID,
-		OldValues: models.JSON{
+		OldValues: auditValues(models.JSON{
 			"title":          post.Title,
 			"description":    post.Description,
 			"address":        post.Address,
@@ -216,16 +466,214 @@ func (s *PostService) DeletePost(userID uint, postID uint) error {
 			"visibility":     post.Visibility,
 			"allow_comments": post.AllowComments,
 			"status":         post.Status,
-		},
+		}),
 	}
 	return s.auditRepo.CreateLog(auditLog)
 }
 
-func (s *PostService) ListPosts(page, pageSize int, userID *uint) ([]models.Post, int64, error) {
-	return s.postRepo.ListPosts(page, pageSize, userID)
+// RestorePost takes a post out of the trash. Owners may restore their own
+// posts within the restore window, holders of posts:restore:any at any time.
+func (s *PostService) RestorePost(userID uint, postID uint) (*models.Post, error) {
+	post, err := s.postRepo.GetDeletedPostByID(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.policy.Authorize(userID, "posts", "restore:any"); err != nil {
+		if err := s.policy.AuthorizeOwned(userID, "posts", "restore", post.UserID, post.ID); err != nil {
+			return nil, err
+		}
+		if time.Since(post.DeletedAt.Time) > s.restoreWindow {
+			return nil, ErrRestoreWindowExpired
+		}
+	}
+
+	if err := s.postRepo.RestorePost(post.ID); err != nil {
+		return nil, err
+	}
+
+	auditLog := &models.AuditLog{
+		UserID:    &userID,
+		Action:    "restore",
+		TableName: "posts",
+		RecordID:  post.ID,
+		OldValues: models.JSON{"deleted_at": post.DeletedAt.Time},
+		NewValues: models.JSON{"deleted_at": nil},
+	}
+	if err := s.auditRepo.CreateLog(auditLog); err != nil {
+		log.Printf("Failed to create audit log for restoring post %d: %v", post.ID, err)
+	}
+
+	log.Printf("Post %d restored by user %d", post.ID, userID)
+	return s.redactedPost(userID, post.ID)
+}
+
+// ListTrash lists deleted posts: every user's for holders of
+// posts:restore:any, otherwise only the caller's own.
+func (s *PostService) ListTrash(userID uint, page, pageSize int) ([]models.Post, int64, error) {
+	owner := &userID
+	if s.policy.Authorize(userID, "posts", "restore:any") == nil {
+		owner = nil
+	}
+	if page < 1 {
+		page = 1
+	}
+	posts, total, err := s.postRepo.ListDeletedPosts(owner, page, pagination.ClampSize(pageSize))
+	if err != nil {
+		return nil, 0, err
+	}
+	s.redactor.viewer(userID).posts(posts)
+	return posts, total, nil
+}
+
+// PurgeDeletedPosts permanently removes posts that have been in the trash
+// longer than the retention period. Purge hooks run first; if one fails the
+// batch is kept and retried on the next run.
+func (s *PostService) PurgeDeletedPosts() (int64, error) {
+	ids, err := s.postRepo.ListPurgeablePostIDs(time.Now().Add(-s.trashRetention), purgeBatchSize)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	for _, hook := range s.purgeHooks {
+		if err := hook(ids); err != nil {
+			return 0, err
+		}
+	}
+
+	purged, err := s.postRepo.PurgePosts(ids)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("Purged %d posts deleted more than %s ago", purged, s.trashRetention)
+	return purged, nil
+}
+
+// OnPurge registers a function that is called with the IDs of posts about
+// to be purged, so that data kept outside the database can be removed too.
+func (s *PostService) OnPurge(hook func(postIDs []uint) error) {
+	s.purgeHooks = append(s.purgeHooks, hook)
+}
+
+// StartPurgeJob runs PurgeDeletedPosts every interval for the lifetime of
+// the process.
+func (s *PostService) StartPurgeJob(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.PurgeDeletedPosts(); err != nil {
+				log.Printf("Failed to purge deleted posts: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+func (s *PostService) ListPosts(filter models.PostFilter, page, pageSize int, userID *uint) ([]models.Post, int64, error) {
+	filter, includePrivate, empty, err := s.preparePostFilter(filter, userID)
+	if err != nil || empty {
+		return []models.Post{}, 0, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	posts, total, err := s.postRepo.ListPosts(filter, page, pagination.ClampSize(pageSize), userID, includePrivate)
+	if err != nil {
+		return nil, 0, err
+	}
+	s.redactor.viewerOf(userID).posts(posts)
+	return posts, total, nil
+}
+
+// ListPostsByCursor returns the page of posts after (or before) the cursor
+// token; an empty token starts at the first page.
+func (s *PostService) ListPostsByCursor(filter models.PostFilter, token string, pageSize int, userID *uint) ([]models.Post, pagination.Cursors, error) {
+	cursor, err := pagination.Decode(token)
+	if err != nil {
+		return nil, pagination.Cursors{}, err
+	}
+
+	filter, includePrivate, empty, err := s.preparePostFilter(filter, userID)
+	if err != nil || empty {
+		return []models.Post{}, pagination.Cursors{}, err
+	}
+	posts, cursors, err := s.postRepo.ListPostsByCursor(filter, cursor, pagination.ClampSize(pageSize), userID, includePrivate)
+	if err != nil {
+		return nil, pagination.Cursors{}, err
+	}
+	s.redactor.viewerOf(userID).posts(posts)
+	return posts, cursors, nil
+}
+
+// preparePostFilter validates a filter and applies the caller's visibility.
+// empty is set when the filter cannot match anything the caller may see.
+func (s *PostService) preparePostFilter(filter models.PostFilter, userID *uint) (models.PostFilter, bool, bool, error) {
+	if err := validatePostFilter(filter); err != nil {
+		return filter, false, false, err
+	}
+	if filter.Tag != "" {
+		tag, err := normalizeTag(filter.Tag)
+		if err != nil {
+			return filter, false, false, &FilterError{Field: "tag", Message: err.Error()}
+		}
+		filter.Tag = tag
+	}
+	if filter.Phone != "" {
+		number, err := s.phones.Normalize(filter.Phone)
+		if err != nil {
+			return filter, false, false, &FilterError{Field: "phone", Message: err.Error()}
+		}
+		if filter.PhoneIndex, err = fieldcrypt.BlindIndex(models.PostMobileIndex, number); err != nil {
+			return filter, false, false, err
+		}
+	}
+
+	includePrivate := false
+	if userID != nil {
+		if err := s.policy.Authorize(*userID, "posts", "read"); err != nil {
+			return filter, false, false, err
+		}
+		includePrivate = s.policy.Authorize(*userID, "posts", "read_private") == nil
+	}
+
+	// Filtering anonymous posts by author would reveal who wrote them
+	if filter.AuthorID != nil && !includePrivate && (userID == nil || *filter.AuthorID != *userID) {
+		if filter.IsAnonymous != nil && *filter.IsAnonymous {
+			return filter, includePrivate, true, nil
+		}
+		anonymous := false
+		filter.IsAnonymous = &anonymous
+	}
+	return filter, includePrivate, false, nil
+}
+
+// validatePostFilter checks what the binding tags cannot: that every date
+// range is in order.
+func validatePostFilter(filter models.PostFilter) error {
+	if filter.IncidentFrom != nil && filter.IncidentTo != nil && filter.IncidentFrom.After(*filter.IncidentTo) {
+		return &FilterError{Field: "incident_from", Message: "must not be after incident_to"}
+	}
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && filter.CreatedFrom.After(*filter.CreatedTo) {
+		return &FilterError{Field: "created_from", Message: "must not be after created_to"}
+	}
+	return nil
 }
 
 func (s *PostService) CreateReport(userID uint, postID uint, req models.CreateReportRequest) error {
+	if err := s.policy.Authorize(userID, "reports", "create"); err != nil {
+		return err
+	}
+
+	// Users can only report posts they are able to see
+	post, err := s.postRepo.GetPostByID(postID)
+	if err != nil {
+		return err
+	}
+	if err := s.policy.AuthorizePostView(userID, post); err != nil {
+		return err
+	}
+
 	report := &models.Report{
 		PostID:     postID,
 		ReporterID: userID,
@@ -254,6 +702,10 @@ func (s *PostService) CreateReport(userID uint, postID uint, req models.CreateRe
 }
 
 func (s *PostService) UpdateReportStatus(userID uint, reportID uint, status string) error {
+	if err := s.policy.Authorize(userID, "reports", "resolve"); err != nil {
+		return err
+	}
+
 	report, err := s.postRepo.GetReportByID(reportID)
 	if err != nil {
 		return err
@@ -280,10 +732,71 @@ func (s *PostService) UpdateReportStatus(userID uint, reportID uint, status stri
 	return s.auditRepo.CreateLog(auditLog)
 }
 
-func (s *PostService) ListReports(page, pageSize int) ([]models.Report, int64, error) {
-	return s.postRepo.ListReports(page, pageSize)
+func (s *PostService) ListReports(userID uint, page, pageSize int) ([]models.Report, int64, error) {
+	if err := s.policy.Authorize(userID, "reports", "read"); err != nil {
+		return nil, 0, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	reports, total, err := s.postRepo.ListReports(page, pagination.ClampSize(pageSize))
+	if err != nil {
+		return nil, 0, err
+	}
+	s.redactor.viewer(userID).reports(reports)
+	return reports, total, nil
+}
+
+func (s *PostService) ListReportsByCursor(userID uint, token string, pageSize int) ([]models.Report, pagination.Cursors, error) {
+	if err := s.policy.Authorize(userID, "reports", "read"); err != nil {
+		return nil, pagination.Cursors{}, err
+	}
+	cursor, err := pagination.Decode(token)
+	if err != nil {
+		return nil, pagination.Cursors{}, err
+	}
+	reports, cursors, err := s.postRepo.ListReportsByCursor(cursor, pagination.ClampSize(pageSize))
+	if err != nil {
+		return nil, pagination.Cursors{}, err
+	}
+	s.redactor.viewer(userID).reports(reports)
+	return reports, cursors, nil
 }
 
-func (s *PostService) GetPostHistory(postID uint) ([]models.PostHistory, error) {
-	return s.postRepo.GetPostHistory(postID)
+func (s *PostService) GetPostHistory(userID uint, postID uint) ([]models.PostHistory, error) {
+	post, err := s.postRepo.GetPostByID(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.policy.AuthorizeOwned(userID, "posts", "history", post.UserID, post.ID); err != nil {
+		return nil, err
+	}
+	history, err := s.postRepo.GetPostHistory(postID)
+	if err != nil {
+		return nil, err
+	}
+	s.redactor.viewer(userID).history(history)
+	return history, nil
+}
+
+func (s *PostService) GetPostHistoryByCursor(userID uint, postID uint, token string, pageSize int) ([]models.PostHistory, pagination.Cursors, error) {
+	cursor, err := pagination.Decode(token)
+	if err != nil {
+		return nil, pagination.Cursors{}, err
+	}
+
+	post, err := s.postRepo.GetPostByID(postID)
+	if err != nil {
+		return nil, pagination.Cursors{}, err
+	}
+	if err := s.policy.AuthorizeOwned(userID, "posts", "history", post.UserID, post.ID); err != nil {
+		return nil, pagination.Cursors{}, err
+	}
+	history, cursors, err := s.postRepo.GetPostHistoryByCursor(postID, cursor, pagination.ClampSize(pageSize))
+	if err != nil {
+		return nil, pagination.Cursors{}, err
+	}
+	s.redactor.viewer(userID).history(history)
+	return history, cursors, nil
 }
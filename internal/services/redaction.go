@@ -0,0 +1,263 @@
+package services
+
+import (
+	"bad_boyes/internal/config"
+	"bad_boyes/internal/models"
+	"bad_boyes/internal/repository"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const maskRune = '*'
+
+// redactor shapes posts before they leave the service so callers only see
+// what they are allowed to. Owners always see their own posts in full.
+// Other users see anonymous posts without their author and see partially
+// masked contact details, unless they hold posts:view_author or
+// posts:view_contact. How much is masked is configured with
+// PII_PHONE_VISIBLE_DIGITS, PII_ADDRESS_VISIBLE_PARTS and
+// PII_COORDINATE_DECIMALS.
+type redactor struct {
+	policy *Policy
+
+	phoneVisibleDigits  int
+	addressVisibleParts int
+	coordinateDecimals  int
+}
+
+func newRedactor(policy *Policy) *redactor {
+	return &redactor{
+		policy:              policy,
+		phoneVisibleDigits:  int(config.GetInt64("PII_PHONE_VISIBLE_DIGITS", 4)),
+		addressVisibleParts: int(config.GetInt64("PII_ADDRESS_VISIBLE_PARTS", 2)),
+		coordinateDecimals:  int(config.GetInt64("PII_COORDINATE_DECIMALS", 2)),
+	}
+}
+
+// viewer is what one user may see of posts that are not theirs.
+type viewer struct {
+	*redactor
+	userID      uint
+	seeAuthors  bool
+	seeContacts bool
+}
+
+func (r *redactor) viewer(userID uint) viewer {
+	return viewer{
+		redactor:    r,
+		userID:      userID,
+		seeAuthors:  r.policy.Authorize(userID, "posts", "view_author") == nil,
+		seeContacts: r.policy.Authorize(userID, "posts", "view_contact") == nil,
+	}
+}
+
+func (r *redactor) viewerOf(userID *uint) viewer {
+	if userID == nil {
+		return viewer{redactor: r}
+	}
+	return r.viewer(*userID)
+}
+
+// owns reports whether a record by the given author belongs to the viewer.
+func (v viewer) owns(authorID uint) bool {
+	return v.userID != 0 && authorID == v.userID
+}
+
+// class names how much of a record by the given author the viewer sees, so
+// differently redacted copies can be told apart.
+func (v viewer) class(authorID uint) string {
+	switch {
+	case v.owns(authorID) || (v.seeAuthors && v.seeContacts):
+		return "full"
+	case v.seeAuthors:
+		return "author"
+	case v.seeContacts:
+		return "contact"
+	default:
+		return "public"
+	}
+}
+
+func (v viewer) post(post *models.Post) {
+	if v.owns(post.UserID) {
+		return
+	}
+
+	if !v.seeAuthors {
+		if post.IsAnonymous {
+			post.UserID = 0
+			post.User = nil
+		} else {
+			post.User = publicUser(post.User)
+		}
+	}
+	if !v.seeContacts {
+		post.MobileNumber = v.maskPhone(post.MobileNumber)
+		if post.MobileNumberE164 != nil {
+			masked := v.maskPhone(*post.MobileNumberE164)
+			post.MobileNumberE164 = &masked
+		}
+		post.Address = v.maskAddress(post.Address)
+		post.Latitude = v.coarsen(post.Latitude)
+		post.Longitude = v.coarsen(post.Longitude)
+	}
+}
+
+// locationDecimals is the precision location queries match posts with.
+// Viewers who are shown coarsened coordinates are matched on those, for
+// their own posts too, so searching cannot reveal more than they see.
+func (v viewer) locationDecimals() int {
+	if v.seeContacts {
+		return repository.ExactLocation
+	}
+	return v.coordinateDecimals
+}
+
+func (v viewer) posts(posts []models.Post) {
+	for i := range posts {
+		v.post(&posts[i])
+	}
+}
+
+// searchResults also drops address highlights, which would show the
+// masked text.
+func (v viewer) searchResults(results []models.PostSearchResult) {
+	for i := range results {
+		if !v.seeContacts && !v.owns(results[i].UserID) {
+			delete(results[i].Highlights, "address")
+		}
+		v.post(&results[i].Post)
+	}
+}
+
+// nearbyResults also rounds distances to 100 m, since exact distances from
+// a few search centers would give away the masked location.
+func (v viewer) nearbyResults(results []models.PostNearbyResult) {
+	for i := range results {
+		if !v.seeContacts && !v.owns(results[i].UserID) {
+			results[i].DistanceKm = math.Round(results[i].DistanceKm*10) / 10
+		}
+		v.post(&results[i].Post)
+	}
+}
+
+// history applies the post rules to history entries, whose UserID is the
+// post's author. On anonymous posts the editor is hidden as well when it is
+// the author.
+func (v viewer) history(entries []models.PostHistory) {
+	for i := range entries {
+		entry := &entries[i]
+		if v.owns(entry.UserID) {
+			continue
+		}
+		if entry.IsAnonymous && !v.seeAuthors {
+			if entry.EditorID != nil && *entry.EditorID == entry.UserID {
+				entry.EditorID = nil
+			}
+			entry.UserID = 0
+		}
+		if !v.seeContacts {
+			entry.MobileNumber = v.maskPhone(entry.MobileNumber)
+			entry.Address = v.maskAddress(entry.Address)
+			entry.Latitude = v.coarsen(entry.Latitude)
+			entry.Longitude = v.coarsen(entry.Longitude)
+		}
+	}
+}
+
+// attachment drops the uploader of an attachment on a post, which would give
+// away the author of an anonymous post.
+func (v viewer) attachment(post *models.Post, attachment *models.Attachment) {
+	if !v.seeAuthors && !v.owns(post.UserID) {
+		attachment.UserID = 0
+	}
+}
+
+func (v viewer) attachments(post *models.Post, attachments []models.Attachment) {
+	for i := range attachments {
+		v.attachment(post, &attachments[i])
+	}
+}
+
+func (v viewer) reports(reports []models.Report) {
+	for i := range reports {
+		report := &reports[i]
+		v.post(&report.Post)
+		if !v.seeAuthors && !v.owns(report.ReporterID) {
+			report.Reporter = publicUser(report.Reporter)
+		}
+	}
+}
+
+func (v viewer) subject(subject *models.Subject) {
+	if !v.seeContacts {
+		subject.Phone = v.maskPhone(subject.Phone)
+	}
+}
+
+// maskPhone hides all but the last digits of a number, keeping its
+// formatting: "+1 (212) 555-1234" becomes "+* (***) ***-1234".
+func (r *redactor) maskPhone(number string) string {
+	digits := 0
+	for _, c := range number {
+		if unicode.IsDigit(c) {
+			digits++
+		}
+	}
+
+	hide := digits - r.phoneVisibleDigits
+	return strings.Map(func(c rune) rune {
+		if unicode.IsDigit(c) && hide > 0 {
+			hide--
+			return maskRune
+		}
+		return c
+	}, number)
+}
+
+// maskAddress keeps the last comma-separated parts of an address, usually
+// the town and region, and replaces the rest. At least the first part is
+// always hidden.
+func (r *redactor) maskAddress(address string) string {
+	if address == "" {
+		return ""
+	}
+	parts := strings.Split(address, ",")
+	visible := r.addressVisibleParts
+	if visible > len(parts)-1 {
+		visible = len(parts) - 1
+	}
+	if visible < 0 {
+		visible = 0
+	}
+
+	masked := []string{strings.Repeat(string(maskRune), 3)}
+	for _, part := range parts[len(parts)-visible:] {
+		masked = append(masked, strings.TrimSpace(part))
+	}
+	return strings.Join(masked, ", ")
+}
+
+// coarsen rounds a coordinate so it no longer points at an exact address;
+// two decimals is about a kilometre.
+func (r *redactor) coarsen(coordinate *float64) *float64 {
+	if coordinate == nil {
+		return nil
+	}
+	scale := math.Pow(10, float64(r.coordinateDecimals))
+	rounded := math.Round(*coordinate*scale) / scale
+	return &rounded
+}
+
+// publicUser keeps only the fields of a user that anyone may see.
+func publicUser(user *models.User) *models.User {
+	if user == nil {
+		return nil
+	}
+	return &models.User{
+		ID:       user.ID,
+		Username: user.Username,
+		Name:     user.Name,
+	}
+}
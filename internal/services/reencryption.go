@@ -0,0 +1,270 @@
+package services
+
+import (
+	"bad_boyes/internal/fieldcrypt"
+	"bad_boyes/internal/models"
+	"bad_boyes/internal/repository"
+	"fmt"
+	"log"
+)
+
+// ReencryptionResult counts what a re-encryption run did to one table.
+type ReencryptionResult struct {
+	Table   string
+	Scanned int
+	Updated int
+	Merged  int
+}
+
+// encryptedTable lists the encrypted columns of a table and the blind index
+// columns derived from them. searchTokens, when set, derives the blind
+// indexes kept in post_search_tokens.
+type encryptedTable struct {
+	name         string
+	columns      []string
+	indexColumns []string
+	indexes      func(ring *fieldcrypt.KeyRing, plain map[string]*string) map[string]interface{}
+	searchTokens func(plain map[string]*string) ([]string, error)
+}
+
+var encryptedTables = []encryptedTable{
+	{
+		name:         "posts",
+		columns:      []string{"address", "contact_name", "mobile_number", "mobile_number_e164"},
+		indexColumns: []string{"mobile_number_e164_bidx"},
+		indexes: func(ring *fieldcrypt.KeyRing, plain map[string]*string) map[string]interface{} {
+			var index interface{}
+			if number := plain["mobile_number_e164"]; number != nil {
+				index = ring.BlindIndex(models.PostMobileIndex, *number)
+			}
+			return map[string]interface{}{"mobile_number_e164_bidx": index}
+		},
+		searchTokens: func(plain map[string]*string) ([]string, error) {
+			return models.SearchTokens(stringValue(plain["address"]) + " " + stringValue(plain["contact_name"]))
+		},
+	},
+	{
+		name:    "post_history",
+		columns: []string{"address", "contact_name", "mobile_number"},
+	},
+	{
+		name:         "subjects",
+		columns:      []string{"phone", "name"},
+		indexColumns: []string{"phone_bidx", "name_key"},
+		indexes: func(ring *fieldcrypt.KeyRing, plain map[string]*string) map[string]interface{} {
+			return map[string]interface{}{
+				"phone_bidx": ring.BlindIndex(models.SubjectPhoneIndex, stringValue(plain["phone"])),
+				"name_key":   ring.BlindIndex(models.SubjectNameIndex, subjectNameKey(stringValue(plain["name"]))),
+			}
+		},
+	},
+}
+
+// encryptionRepository is the raw row access FieldReencryption works with,
+// implemented by *repository.EncryptionRepository.
+type encryptionRepository interface {
+	ListRawRows(table string, columns []string, afterID uint, limit int) ([]map[string]interface{}, error)
+	UpdateRawRow(table string, id uint, values map[string]interface{}) error
+	ReplacePostSearchTokens(postID uint, tokens []string) error
+	FindSubjectByIndexes(phoneIndex, nameKey string, exceptID uint) (uint, error)
+}
+
+// FieldReencryption moves encrypted columns to the active key after a key
+// rotation, encrypts rows written before encryption was enabled and fills
+// in missing or stale blind indexes.
+type FieldReencryption struct {
+	encryptionRepo encryptionRepository
+	subjectRepo    *repository.SubjectRepository
+	ring           *fieldcrypt.KeyRing
+}
+
+func NewFieldReencryption(encryptionRepo *repository.EncryptionRepository, subjectRepo *repository.SubjectRepository, ring *fieldcrypt.KeyRing) *FieldReencryption {
+	return &FieldReencryption{
+		encryptionRepo: encryptionRepo,
+		subjectRepo:    subjectRepo,
+		ring:           ring,
+	}
+}
+
+// Run walks every table with encrypted columns in batches. Encrypted
+// values are re-wrapped, so only their data keys are sealed again; plaintext
+// values are encrypted. Subjects whose blind indexes turn out to match another
+// subject are merged into the older one, and the search tokens of every post
+// are rewritten. With dryRun nothing is written.
+func (f *FieldReencryption) Run(batchSize int, dryRun bool) ([]ReencryptionResult, error) {
+	var results []ReencryptionResult
+	for _, table := range encryptedTables {
+		result, err := f.reencryptTable(table, batchSize, dryRun)
+		results = append(results, result)
+		if err != nil {
+			return results, fmt.Errorf("%s: %w", table.name, err)
+		}
+		log.Printf("Re-encrypted %s: %d rows scanned, %d updated, %d merged", result.Table, result.Scanned, result.Updated, result.Merged)
+	}
+	return results, nil
+}
+
+func (f *FieldReencryption) reencryptTable(table encryptedTable, batchSize int, dryRun bool) (ReencryptionResult, error) {
+	result := ReencryptionResult{Table: table.name}
+	columns := append(append([]string{}, table.columns...), table.indexColumns...)
+	var afterID uint
+
+	for {
+		rows, err := f.encryptionRepo.ListRawRows(table.name, columns, afterID, batchSize)
+		if err != nil {
+			return result, err
+		}
+		if len(rows) == 0 {
+			return result, nil
+		}
+
+		for _, row := range rows {
+			id, err := rowID(row["id"])
+			if err != nil {
+				return result, err
+			}
+			afterID = id
+			result.Scanned++
+
+			updates, plain, err := f.rowUpdates(table, row)
+			if err != nil {
+				return result, fmt.Errorf("row %d: %w", id, err)
+			}
+			if table.searchTokens != nil && !dryRun {
+				tokens, err := table.searchTokens(plain)
+				if err == nil {
+					err = f.encryptionRepo.ReplacePostSearchTokens(id, tokens)
+				}
+				if err != nil {
+					return result, fmt.Errorf("row %d: search tokens: %w", id, err)
+				}
+			}
+			if len(updates) == 0 {
+				continue
+			}
+			if dryRun {
+				result.Updated++
+				continue
+			}
+
+			if table.name == "subjects" {
+				merged, err := f.mergeDuplicateSubject(id, row, updates)
+				if err != nil {
+					return result, fmt.Errorf("row %d: %w", id, err)
+				}
+				if merged {
+					result.Merged++
+					continue
+				}
+			}
+			if err := f.encryptionRepo.UpdateRawRow(table.name, id, updates); err != nil {
+				return result, fmt.Errorf("row %d: %w", id, err)
+			}
+			result.Updated++
+		}
+	}
+}
+
+// rowUpdates returns the stored values that have to change for a row to be
+// under the active key with current blind indexes, and the decrypted values
+// of its encrypted columns.
+func (f *FieldReencryption) rowUpdates(table encryptedTable, row map[string]interface{}) (map[string]interface{}, map[string]*string, error) {
+	updates := make(map[string]interface{})
+	plain := make(map[string]*string, len(table.columns))
+
+	for _, column := range table.columns {
+		stored := rawString(row[column])
+		if stored == nil {
+			plain[column] = nil
+			continue
+		}
+		value, err := f.ring.Decrypt(*stored)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", column, err)
+		}
+		plain[column] = &value
+
+		if !f.ring.Current(*stored) {
+			rewrapped, err := f.ring.Rewrap(*stored)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", column, err)
+			}
+			updates[column] = rewrapped
+		}
+	}
+
+	if table.indexes != nil {
+		for column, index := range table.indexes(f.ring, plain) {
+			stored := rawString(row[column])
+			if index == nil && stored == nil {
+				continue
+			}
+			if stored != nil && index != nil && *stored == index.(string) {
+				continue
+			}
+			updates[column] = index
+		}
+	}
+	return updates, plain, nil
+}
+
+// mergeDuplicateSubject handles a subject whose new blind indexes match
+// another subject: the newer of the two is merged into the older one. It
+// reports whether the row being processed was merged away.
+func (f *FieldReencryption) mergeDuplicateSubject(id uint, row, updates map[string]interface{}) (bool, error) {
+	phoneIndex, ok := updates["phone_bidx"].(string)
+	if !ok {
+		phoneIndex = stringValue(rawString(row["phone_bidx"]))
+	}
+	nameKey, ok := updates["name_key"].(string)
+	if !ok {
+		nameKey = stringValue(rawString(row["name_key"]))
+	}
+
+	otherID, err := f.encryptionRepo.FindSubjectByIndexes(phoneIndex, nameKey, id)
+	if err != nil || otherID == 0 {
+		return false, err
+	}
+	if otherID < id {
+		return true, f.subjectRepo.MergeSubject(id, otherID)
+	}
+	return false, f.subjectRepo.MergeSubject(otherID, id)
+}
+
+func rowID(value interface{}) (uint, error) {
+	switch v := value.(type) {
+	case int64:
+		return uint(v), nil
+	case uint64:
+		return uint(v), nil
+	case int32:
+		return uint(v), nil
+	case uint32:
+		return uint(v), nil
+	case []byte:
+		var id uint
+		_, err := fmt.Sscan(string(v), &id)
+		return id, err
+	}
+	return 0, fmt.Errorf("unexpected id type %T", value)
+}
+
+// rawString converts a value scanned into a map to a string, or nil for
+// NULL.
+func rawString(value interface{}) *string {
+	switch v := value.(type) {
+	case string:
+		return &v
+	case []byte:
+		s := string(v)
+		return &s
+	}
+	return nil
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
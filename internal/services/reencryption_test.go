@@ -0,0 +1,106 @@
+package services
+
+import (
+	"bad_boyes/internal/fieldcrypt"
+	"bad_boyes/internal/models"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// fakeEncryptionRepository keeps raw rows per table in id order and the
+// search tokens of each post in memory.
+type fakeEncryptionRepository struct {
+	rows   map[string][]map[string]interface{}
+	tokens map[uint][]string
+}
+
+func (r *fakeEncryptionRepository) ListRawRows(table string, columns []string, afterID uint, limit int) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	for _, row := range r.rows[table] {
+		if uint(row["id"].(int64)) <= afterID || len(rows) == limit {
+			continue
+		}
+		selected := map[string]interface{}{"id": row["id"]}
+		for _, column := range columns {
+			selected[column] = row[column]
+		}
+		rows = append(rows, selected)
+	}
+	return rows, nil
+}
+
+func (r *fakeEncryptionRepository) UpdateRawRow(table string, id uint, values map[string]interface{}) error {
+	for _, row := range r.rows[table] {
+		if uint(row["id"].(int64)) == id {
+			for column, value := range values {
+				row[column] = value
+			}
+		}
+	}
+	return nil
+}
+
+func (r *fakeEncryptionRepository) ReplacePostSearchTokens(postID uint, tokens []string) error {
+	r.tokens[postID] = tokens
+	return nil
+}
+
+func (r *fakeEncryptionRepository) FindSubjectByIndexes(phoneIndex, nameKey string, exceptID uint) (uint, error) {
+	return 0, nil
+}
+
+func newTestKeyRing(t *testing.T, fill byte) *fieldcrypt.KeyRing {
+	t.Helper()
+	ring, err := fieldcrypt.NewKeyRing(map[uint32][]byte{1: bytes.Repeat([]byte{fill}, 32)}, 1, bytes.Repeat([]byte{fill + 1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ring
+}
+
+func TestFieldReencryptionSearchTokens(t *testing.T) {
+	ring := newTestKeyRing(t, 1)
+	fieldcrypt.Use(ring)
+	address, err := ring.Encrypt("10 Downing Street, London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := models.SearchTokens("10 Downing Street, London Jane Doe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	staleRing := newTestKeyRing(t, 3)
+	stale := []string{staleRing.BlindIndex(models.PostSearchTokenIndex, "london")}
+
+	for _, tc := range []struct {
+		name   string
+		tokens map[uint][]string
+		dryRun bool
+		want   map[uint][]string
+	}{
+		{"post without token rows", map[uint][]string{}, false, map[uint][]string{1: want}},
+		{"tokens under a rotated index key", map[uint][]string{1: stale}, false, map[uint][]string{1: want}},
+		{"dry run", map[uint][]string{}, true, map[uint][]string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeEncryptionRepository{
+				rows: map[string][]map[string]interface{}{
+					"posts": {{
+						"id":           int64(1),
+						"address":      []byte(address),
+						"contact_name": []byte("Jane Doe"),
+					}},
+				},
+				tokens: tc.tokens,
+			}
+			reencryption := &FieldReencryption{encryptionRepo: repo, ring: ring}
+			if _, err := reencryption.Run(10, tc.dryRun); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if !reflect.DeepEqual(repo.tokens, tc.want) {
+				t.Fatalf("search tokens = %v, want %v", repo.tokens, tc.want)
+			}
+		})
+	}
+}
@@ -1,37 +1,281 @@
 package services
 
 import (
+	"bad_boyes/internal/config"
 	"bad_boyes/internal/models"
 	"bad_boyes/internal/repository"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRoleNotFound              = errors.New("role not found")
+	ErrRoleAlreadyExists         = errors.New("role already exists")
+	ErrRoleProtected             = errors.New("role is required by the application and cannot be changed")
+	ErrInvalidRoleName           = errors.New("role name must start with a letter and contain only lowercase letters, digits, '_' or '-'")
+	ErrRoleAlreadyAssigned       = errors.New("user already has this role")
+	ErrRoleNotAssigned           = errors.New("user does not have this role")
+	ErrPermissionNotFound        = errors.New("permission not found")
+	ErrPermissionAlreadyExists   = errors.New("permission already exists")
+	ErrInvalidPermission         = errors.New("permission resource and action segments must be '*' or start with a letter and contain only lowercase letters, digits, '_' or '-'")
+	ErrPermissionAlreadyAssigned = errors.New("role already has this permission")
+	ErrPermissionNotAssigned     = errors.New("role does not have this permission")
+	ErrRoleCycle                 = errors.New("role cannot inherit from itself or one of its descendants")
 )
 
+// protectedRoles are relied on by registration and administration and may
+// not be renamed or deleted.
+var protectedRoles = map[string]bool{
+	defaultRoleName: true,
+	"admin":         true,
+}
+
+var (
+	identifierPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+	// A resource is a single segment and an action one or more ':' separated
+	// segments, e.g. "update:own". Any segment may be the wildcard "*".
+	resourcePattern = regexp.MustCompile(`^([a-z][a-z0-9_-]*|\*)$`)
+	actionPattern   = regexp.MustCompile(`^([a-z][a-z0-9_-]*|\*)(:([a-z][a-z0-9_-]*|\*))*$`)
+)
+
+// maxRoleDepth bounds how many ancestors are followed when checking role
+// inheritance for cycles.
+const maxRoleDepth = 32
+
+// roleRepository is the role storage RoleService works with, implemented by
+// *repository.RoleRepository.
+type roleRepository interface {
+	CreateRole(role *models.Role) (*models.Role, error)
+	GetRoleByID(id uint) (*models.Role, error)
+	ListRoles() ([]models.Role, error)
+	UpdateRole(role *models.Role) error
+	DeleteRole(id uint) error
+	AssignRoleToUser(userID, roleID uint) error
+	RemoveRoleFromUser(userID, roleID uint) error
+	GetUserRoles(userID uint) ([]models.Role, error)
+	UserExists(userID uint) (bool, error)
+	CreatePermission(permission *models.Permission) (*models.Permission, error)
+	GetPermissionByID(id uint) (*models.Permission, error)
+	ListPermissions() ([]models.Permission, error)
+	UpdatePermission(permission *models.Permission) error
+	DeletePermission(id uint) error
+	AssignPermissionToRole(roleID, permissionID uint) error
+	RemovePermissionFromRole(roleID, permissionID uint) error
+	GetRolePermissions(roleID uint) ([]models.Permission, error)
+	GetUserPermissions(userID uint) ([]models.Permission, error)
+	SetRoleParent(roleID uint, parentID *uint) error
+}
+
+// auditRepository records audit log entries, implemented by
+// *repository.AuditRepository.
+type auditRepository interface {
+	CreateLog(auditLog *models.AuditLog) error
+}
+
 type RoleService struct {
-	roleRepo *repository.RoleRepository
+	roleRepo  roleRepository
+	auditRepo auditRepository
+	cache     *permissionCache
 }
 
-func NewRoleService(roleRepo *repository.RoleRepository) *RoleService {
+func NewRoleService(roleRepo *repository.RoleRepository, auditRepo *repository.AuditRepository) *RoleService {
 	return &RoleService{
-		roleRepo: roleRepo,
+		roleRepo:  roleRepo,
+		auditRepo: auditRepo,
+		cache:     newPermissionCache(config.GetDuration("PERMISSION_CACHE_TTL", defaultPermissionCacheTTL)),
+	}
+}
+
+// ListRoles returns all roles with their permissions
+func (s *RoleService) ListRoles() ([]models.Role, error) {
+	return s.roleRepo.ListRoles()
+}
+
+// GetRole returns a single role with its permissions
+func (s *RoleService) GetRole(roleID uint) (*models.Role, error) {
+	role, err := s.roleRepo.GetRoleByID(roleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
 	}
+	return role, nil
 }
 
-// CreateRole creates a new role
-func (s *RoleService) CreateRole(name, description string) (*models.Role, error) {
+// CreateRole creates a new role, optionally inheriting from a parent role
+func (s *RoleService) CreateRole(actorID uint, name, description string, parentID *uint) (*models.Role, error) {
+	if !identifierPattern.MatchString(name) {
+		return nil, ErrInvalidRoleName
+	}
+	if parentID != nil {
+		if _, err := s.GetRole(*parentID); err != nil {
+			return nil, err
+		}
+	}
+
 	role := &models.Role{
 		Name:        name,
 		Description: description,
+		ParentID:    parentID,
+	}
+	if _, err := s.roleRepo.CreateRole(role); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrRoleAlreadyExists
+		}
+		return nil, err
 	}
-	return s.roleRepo.CreateRole(role)
+
+	s.logRoleEvent(actorID, "create_role", "roles", role.ID, nil, models.JSON{
+		"name":        role.Name,
+		"description": role.Description,
+		"parent_id":   role.ParentID,
+	})
+	return role, nil
+}
+
+// SetRoleParent makes a role inherit the permissions of parentID, or stops
+// inheritance when parentID is nil. Assignments that would create a cycle
+// are rejected.
+func (s *RoleService) SetRoleParent(actorID, roleID uint, parentID *uint) (*models.Role, error) {
+	role, err := s.GetRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID != nil {
+		// Walk up from the new parent; reaching the role itself means a cycle
+		current := *parentID
+		for depth := 0; ; depth++ {
+			if current == roleID || depth >= maxRoleDepth {
+				return nil, ErrRoleCycle
+			}
+			ancestor, err := s.GetRole(current)
+			if err != nil {
+				return nil, err
+			}
+			if ancestor.ParentID == nil {
+				break
+			}
+			current = *ancestor.ParentID
+		}
+	}
+
+	oldParentID := role.ParentID
+	if err := s.roleRepo.SetRoleParent(roleID, parentID); err != nil {
+		return nil, err
+	}
+	s.cache.invalidateAll()
+
+	s.logRoleEvent(actorID, "set_role_parent", "roles", roleID,
+		models.JSON{"parent_id": oldParentID},
+		models.JSON{"parent_id": parentID})
+
+	return s.GetRole(roleID)
+}
+
+// UpdateRole renames a role or changes its description
+func (s *RoleService) UpdateRole(actorID, roleID uint, name, description string) (*models.Role, error) {
+	role, err := s.GetRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	oldValues := models.JSON{"name": role.Name, "description": role.Description}
+
+	if name != "" && name != role.Name {
+		if protectedRoles[role.Name] {
+			return nil, ErrRoleProtected
+		}
+		if !identifierPattern.MatchString(name) {
+			return nil, ErrInvalidRoleName
+		}
+		role.Name = name
+	}
+	role.Description = description
+
+	if err := s.roleRepo.UpdateRole(role); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrRoleAlreadyExists
+		}
+		return nil, err
+	}
+
+	s.logRoleEvent(actorID, "update_role", "roles", role.ID, oldValues, models.JSON{
+		"name":        role.Name,
+		"description": role.Description,
+	})
+	return role, nil
+}
+
+// DeleteRole deletes a role along with its user and permission assignments
+func (s *RoleService) DeleteRole(actorID, roleID uint) error {
+	role, err := s.GetRole(roleID)
+	if err != nil {
+		return err
+	}
+	if protectedRoles[role.Name] {
+		return ErrRoleProtected
+	}
+
+	if err := s.roleRepo.DeleteRole(roleID); err != nil {
+		return err
+	}
+	s.cache.invalidateAll()
+
+	s.logRoleEvent(actorID, "delete_role", "roles", roleID, models.JSON{
+		"name":        role.Name,
+		"description": role.Description,
+	}, nil)
+	return nil
 }
 
 // AssignRoleToUser assigns a role to a user
-func (s *RoleService) AssignRoleToUser(userID, roleID uint) error {
-	return s.roleRepo.AssignRoleToUser(userID, roleID)
+func (s *RoleService) AssignRoleToUser(actorID, userID, roleID uint) error {
+	if err := s.ensureUserExists(userID); err != nil {
+		return err
+	}
+	role, err := s.GetRole(roleID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.roleRepo.AssignRoleToUser(userID, roleID); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return ErrRoleAlreadyAssigned
+		}
+		return err
+	}
+	s.cache.invalidate(userID)
+
+	s.logRoleEvent(actorID, "grant_role", "user_roles", userID, nil, models.JSON{
+		"user_id":   userID,
+		"role_id":   roleID,
+		"role_name": role.Name,
+	})
+	return nil
 }
 
 // RemoveRoleFromUser removes a role from a user
-func (s *RoleService) RemoveRoleFromUser(userID, roleID uint) error {
-	return s.roleRepo.RemoveRoleFromUser(userID, roleID)
+func (s *RoleService) RemoveRoleFromUser(actorID, userID, roleID uint) error {
+	if err := s.roleRepo.RemoveRoleFromUser(userID, roleID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRoleNotAssigned
+		}
+		return err
+	}
+	s.cache.invalidate(userID)
+
+	s.logRoleEvent(actorID, "revoke_role", "user_roles", userID, models.JSON{
+		"user_id": userID,
+		"role_id": roleID,
+	}, nil)
+	return nil
 }
 
 // GetUserRoles returns all roles assigned to a user
@@ -39,51 +283,224 @@ func (s *RoleService) GetUserRoles(userID uint) ([]models.Role, error) {
 	return s.roleRepo.GetUserRoles(userID)
 }
 
-// CreatePermission creates a new permission
-func (s *RoleService) CreatePermission(name, description, resource, action string) (*models.Permission, error) {
+// ListPermissions returns the permission catalogue
+func (s *RoleService) ListPermissions() ([]models.Permission, error) {
+	return s.roleRepo.ListPermissions()
+}
+
+// CreatePermission creates a new permission named "<resource>:<action>".
+// Wildcards such as "posts:*" or "*:*" grant every matching permission.
+func (s *RoleService) CreatePermission(actorID uint, description, resource, action string) (*models.Permission, error) {
+	if !resourcePattern.MatchString(resource) || !actionPattern.MatchString(action) {
+		return nil, ErrInvalidPermission
+	}
+
 	permission := &models.Permission{
-		Name:        name,
+		Name:        resource + ":" + action,
 		Description: description,
 		Resource:    resource,
 		Action:      action,
 	}
-	return s.roleRepo.CreatePermission(permission)
+	if _, err := s.roleRepo.CreatePermission(permission); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrPermissionAlreadyExists
+		}
+		return nil, err
+	}
+
+	s.logRoleEvent(actorID, "create_permission", "permissions", permission.ID, nil, models.JSON{
+		"name":        permission.Name,
+		"description": permission.Description,
+	})
+	return permission, nil
+}
+
+// UpdatePermission changes the description of a permission
+func (s *RoleService) UpdatePermission(actorID, permissionID uint, description string) (*models.Permission, error) {
+	permission, err := s.getPermission(permissionID)
+	if err != nil {
+		return nil, err
+	}
+
+	oldDescription := permission.Description
+	permission.Description = description
+	if err := s.roleRepo.UpdatePermission(permission); err != nil {
+		return nil, err
+	}
+
+	s.logRoleEvent(actorID, "update_permission", "permissions", permission.ID,
+		models.JSON{"description": oldDescription},
+		models.JSON{"description": permission.Description})
+	return permission, nil
+}
+
+// DeletePermission deletes a permission and revokes it from every role
+func (s *RoleService) DeletePermission(actorID, permissionID uint) error {
+	permission, err := s.getPermission(permissionID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.roleRepo.DeletePermission(permissionID); err != nil {
+		return err
+	}
+	s.cache.invalidateAll()
+
+	s.logRoleEvent(actorID, "delete_permission", "permissions", permissionID, models.JSON{
+		"name":        permission.Name,
+		"description": permission.Description,
+	}, nil)
+	return nil
 }
 
 // AssignPermissionToRole assigns a permission to a role
-func (s *RoleService) AssignPermissionToRole(roleID, permissionID uint) error {
-	return s.roleRepo.AssignPermissionToRole(roleID, permissionID)
+func (s *RoleService) AssignPermissionToRole(actorID, roleID, permissionID uint) error {
+	role, err := s.GetRole(roleID)
+	if err != nil {
+		return err
+	}
+	permission, err := s.getPermission(permissionID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.roleRepo.AssignPermissionToRole(roleID, permissionID); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return ErrPermissionAlreadyAssigned
+		}
+		return err
+	}
+	s.cache.invalidateAll()
+
+	s.logRoleEvent(actorID, "grant_permission", "role_permissions", roleID, nil, models.JSON{
+		"role_id":         roleID,
+		"role_name":       role.Name,
+		"permission_id":   permissionID,
+		"permission_name": permission.Name,
+	})
+	return nil
 }
 
 // RemovePermissionFromRole removes a permission from a role
-func (s *RoleService) RemovePermissionFromRole(roleID, permissionID uint) error {
-	return s.roleRepo.RemovePermissionFromRole(roleID, permissionID)
+func (s *RoleService) RemovePermissionFromRole(actorID, roleID, permissionID uint) error {
+	if err := s.roleRepo.RemovePermissionFromRole(roleID, permissionID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPermissionNotAssigned
+		}
+		return err
+	}
+	s.cache.invalidateAll()
+
+	s.logRoleEvent(actorID, "revoke_permission", "role_permissions", roleID, models.JSON{
+		"role_id":       roleID,
+		"permission_id": permissionID,
+	}, nil)
+	return nil
 }
 
 // GetRolePermissions returns all permissions assigned to a role
 func (s *RoleService) GetRolePermissions(roleID uint) ([]models.Permission, error) {
+	if _, err := s.GetRole(roleID); err != nil {
+		return nil, err
+	}
 	return s.roleRepo.GetRolePermissions(roleID)
 }
 
-// CheckPermission checks if a user has a specific permission
+// CheckPermission checks if a user has a specific permission, either
+// directly or through a wildcard or an inherited role
 func (s *RoleService) CheckPermission(userID uint, resource, action string) (bool, error) {
-	roles, err := s.GetUserRoles(userID)
+	permissions, err := s.effectivePermissions(userID)
 	if err != nil {
 		return false, err
 	}
 
-	for _, role := range roles {
-		permissions, err := s.GetRolePermissions(role.ID)
-		if err != nil {
-			return false, err
+	required := resource + ":" + action
+	if _, ok := permissions[required]; ok {
+		return true, nil
+	}
+	for granted := range permissions {
+		if permissionMatches(granted, required) {
+			return true, nil
 		}
+	}
+	return false, nil
+}
 
-		for _, permission := range permissions {
-			if permission.Resource == resource && permission.Action == action {
-				return true, nil
-			}
+// permissionMatches reports whether the granted permission covers the
+// required one. Permissions are ':' separated paths of the same length where
+// "*" matches any single segment. Only a trailing "*" also covers deeper
+// levels, so "posts:*" covers "posts:update:own" and "*:*" covers everything,
+// but "posts:update" does not cover "posts:update:any".
+func permissionMatches(granted, required string) bool {
+	g := strings.Split(granted, ":")
+	r := strings.Split(required, ":")
+	last := len(g) - 1
+	if len(g) > len(r) || (len(g) < len(r) && g[last] != "*") {
+		return false
+	}
+	for i := range g {
+		if g[i] != "*" && g[i] != r[i] {
+			return false
 		}
 	}
+	return true
+}
 
-	return false, nil
+// effectivePermissions returns the set of "<resource>:<action>" keys granted
+// to the user, served from the cache when possible.
+func (s *RoleService) effectivePermissions(userID uint) (map[string]struct{}, error) {
+	permissions, generation, ok := s.cache.get(userID)
+	if ok {
+		return permissions, nil
+	}
+
+	granted, err := s.roleRepo.GetUserPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions = make(map[string]struct{}, len(granted))
+	for _, permission := range granted {
+		permissions[permission.Resource+":"+permission.Action] = struct{}{}
+	}
+	s.cache.set(userID, permissions, generation)
+	return permissions, nil
+}
+
+func (s *RoleService) getPermission(permissionID uint) (*models.Permission, error) {
+	permission, err := s.roleRepo.GetPermissionByID(permissionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPermissionNotFound
+		}
+		return nil, err
+	}
+	return permission, nil
+}
+
+func (s *RoleService) ensureUserExists(userID uint) error {
+	exists, err := s.roleRepo.UserExists(userID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// logRoleEvent writes an audit log entry for a change to roles or
+// permissions. Failures are logged but never fail the request.
+func (s *RoleService) logRoleEvent(actorID uint, action, tableName string, recordID uint, oldValues, newValues models.JSON) {
+	auditLog := &models.AuditLog{
+		UserID:    &actorID,
+		Action:    action,
+		TableName: tableName,
+		RecordID:  recordID,
+		OldValues: oldValues,
+		NewValues: newValues,
+	}
+	if err := s.auditRepo.CreateLog(auditLog); err != nil {
+		log.Printf("Failed to create audit log for %s: %v", action, err)
+	}
 }
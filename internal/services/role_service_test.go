@@ -0,0 +1,331 @@
+package services
+
+import (
+	"bad_boyes/internal/models"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeRoleRepository keeps roles, grants and role assignments in memory and
+// counts permission lookups. Only what RoleService needs for permission
+// checks, inheritance and grant changes is implemented.
+type fakeRoleRepository struct {
+	roleRepository
+
+	lookups         int
+	roles           map[uint]*models.Role
+	permissions     map[uint]models.Permission
+	rolePermissions map[uint][]uint
+	userRoles       map[uint][]uint
+}
+
+func (r *fakeRoleRepository) GetRoleByID(id uint) (*models.Role, error) {
+	role, ok := r.roles[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *role
+	return &copied, nil
+}
+
+func (r *fakeRoleRepository) SetRoleParent(roleID uint, parentID *uint) error {
+	r.roles[roleID].ParentID = parentID
+	return nil
+}
+
+func (r *fakeRoleRepository) UserExists(userID uint) (bool, error) {
+	return true, nil
+}
+
+func (r *fakeRoleRepository) AssignRoleToUser(userID, roleID uint) error {
+	r.userRoles[userID] = append(r.userRoles[userID], roleID)
+	return nil
+}
+
+func (r *fakeRoleRepository) RemoveRoleFromUser(userID, roleID uint) error {
+	r.userRoles[userID] = without(r.userRoles[userID], roleID)
+	return nil
+}
+
+func (r *fakeRoleRepository) GetPermissionByID(id uint) (*models.Permission, error) {
+	permission, ok := r.permissions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &permission, nil
+}
+
+func (r *fakeRoleRepository) AssignPermissionToRole(roleID, permissionID uint) error {
+	r.rolePermissions[roleID] = append(r.rolePermissions[roleID], permissionID)
+	return nil
+}
+
+func (r *fakeRoleRepository) RemovePermissionFromRole(roleID, permissionID uint) error {
+	r.rolePermissions[roleID] = without(r.rolePermissions[roleID], permissionID)
+	return nil
+}
+
+// GetUserPermissions resolves the permissions of every role of the user and
+// of their ancestors, like the recursive query of the real repository.
+func (r *fakeRoleRepository) GetUserPermissions(userID uint) ([]models.Permission, error) {
+	r.lookups++
+	var permissions []models.Permission
+	for _, roleID := range r.userRoles[userID] {
+		for role := r.roles[roleID]; role != nil; {
+			for _, id := range r.rolePermissions[role.ID] {
+				permissions = append(permissions, r.permissions[id])
+			}
+			if role.ParentID == nil {
+				break
+			}
+			role = r.roles[*role.ParentID]
+		}
+	}
+	return permissions, nil
+}
+
+func without(ids []uint, id uint) []uint {
+	var kept []uint
+	for _, other := range ids {
+		if other != id {
+			kept = append(kept, other)
+		}
+	}
+	return kept
+}
+
+type fakeAuditRepository struct{}
+
+func (fakeAuditRepository) CreateLog(auditLog *models.AuditLog) error {
+	return nil
+}
+
+// Roles: moderator inherits from user, admin from moderator, and editor
+// stands alone. Users: 1 is a user, 2 a moderator, 3 an admin and 4 an
+// editor.
+func newFakeRoleRepository() *fakeRoleRepository {
+	parent := func(id uint) *uint { return &id }
+	return &fakeRoleRepository{
+		roles: map[uint]*models.Role{
+			1: {ID: 1, Name: "user"},
+			2: {ID: 2, Name: "moderator", ParentID: parent(1)},
+			3: {ID: 3, Name: "admin", ParentID: parent(2)},
+			4: {ID: 4, Name: "editor"},
+		},
+		permissions: map[uint]models.Permission{
+			1: {ID: 1, Name: "posts:read", Resource: "posts", Action: "read"},
+			2: {ID: 2, Name: "posts:create", Resource: "posts", Action: "create"},
+			3: {ID: 3, Name: "reports:*", Resource: "reports", Action: "*"},
+			4: {ID: 4, Name: "*:*", Resource: "*", Action: "*"},
+			5: {ID: 5, Name: "posts:update", Resource: "posts", Action: "update"},
+		},
+		rolePermissions: map[uint][]uint{1: {1, 2}, 2: {3}, 3: {4}, 4: {5}},
+		userRoles:       map[uint][]uint{1: {1}, 2: {2}, 3: {3}, 4: {4}},
+	}
+}
+
+func newTestRoleService(repo *fakeRoleRepository, ttl time.Duration) *RoleService {
+	return &RoleService{roleRepo: repo, auditRepo: fakeAuditRepository{}, cache: newPermissionCache(ttl)}
+}
+
+func TestPermissionMatches(t *testing.T) {
+	for _, tc := range []struct {
+		granted, required string
+		want              bool
+	}{
+		{"posts:read", "posts:read", true},
+		{"posts:read", "posts:create", false},
+		{"posts:read", "comments:read", false},
+		{"posts:*", "posts:read", true},
+		{"posts:*", "comments:read", false},
+		{"*:read", "posts:read", true},
+		{"*:read", "posts:create", false},
+		{"*:*", "posts:read", true},
+		{"posts:*", "posts:update:own", true},
+		{"*:*", "posts:update:own", true},
+		{"posts:update", "posts:update:own", false},
+		{"posts:update:own", "posts:update", false},
+		{"posts:*:own", "posts:update:own", true},
+		{"posts:*:own", "posts:update:any", false},
+		{"posts", "posts:read", false},
+		{"posts:read", "posts:reader", false},
+	} {
+		if got := permissionMatches(tc.granted, tc.required); got != tc.want {
+			t.Errorf("permissionMatches(%q, %q) = %v, want %v", tc.granted, tc.required, got, tc.want)
+		}
+	}
+}
+
+func TestCheckPermissionInheritance(t *testing.T) {
+	service := newTestRoleService(newFakeRoleRepository(), 0)
+
+	for _, tc := range []struct {
+		name             string
+		userID           uint
+		resource, action string
+		want             bool
+	}{
+		{"direct grant", 1, "posts", "read", true},
+		{"not granted", 1, "reports", "read", false},
+		{"inherited from parent", 2, "posts", "create", true},
+		{"own wildcard", 2, "reports", "resolve", true},
+		{"not granted to any ancestor", 2, "roles", "manage", false},
+		{"inherited from grandparent", 3, "posts", "read", true},
+		{"own global wildcard", 3, "roles", "manage", true},
+		{"unrelated role", 4, "posts", "read", false},
+		{"unrelated role grant", 4, "posts", "update", true},
+		{"no roles", 5, "posts", "read", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := service.CheckPermission(tc.userID, tc.resource, tc.action)
+			if err != nil || got != tc.want {
+				t.Fatalf("CheckPermission(%d, %s:%s) = %v, %v; want %v", tc.userID, tc.resource, tc.action, got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestSetRoleParent(t *testing.T) {
+	parent := func(id uint) *uint { return &id }
+
+	for _, tc := range []struct {
+		name     string
+		roleID   uint
+		parentID *uint
+		err      error
+	}{
+		{"own parent", 1, parent(1), ErrRoleCycle},
+		{"descendant as parent", 1, parent(3), ErrRoleCycle},
+		{"child as parent", 2, parent(3), ErrRoleCycle},
+		{"unrelated parent", 4, parent(3), nil},
+		{"remove parent", 3, nil, nil},
+		{"missing role", 9, parent(1), ErrRoleNotFound},
+		{"missing parent", 4, parent(9), ErrRoleNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newFakeRoleRepository()
+			service := newTestRoleService(repo, 0)
+			_, err := service.SetRoleParent(1, tc.roleID, tc.parentID)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("SetRoleParent(%d, %v) error = %v, want %v", tc.roleID, tc.parentID, err, tc.err)
+			}
+		})
+	}
+}
+
+// TestPermissionCacheInvalidation checks that checks are answered from the
+// cache until a grant changes, and that every change is seen right away.
+func TestPermissionCacheInvalidation(t *testing.T) {
+	parent := func(id uint) *uint { return &id }
+
+	for _, tc := range []struct {
+		name             string
+		userID           uint
+		resource, action string
+		before, after    bool
+		change           func(s *RoleService) error
+	}{
+		{"role assigned", 1, "reports", "read", false, true, func(s *RoleService) error {
+			return s.AssignRoleToUser(1, 1, 2)
+		}},
+		{"role removed", 2, "reports", "read", true, false, func(s *RoleService) error {
+			return s.RemoveRoleFromUser(1, 2, 2)
+		}},
+		{"permission granted to an ancestor", 3, "posts", "update", true, true, func(s *RoleService) error {
+			return s.AssignPermissionToRole(1, 1, 5)
+		}},
+		{"permission granted to a parent", 2, "posts", "update", false, true, func(s *RoleService) error {
+			return s.AssignPermissionToRole(1, 1, 5)
+		}},
+		{"permission revoked from a parent", 2, "posts", "create", true, false, func(s *RoleService) error {
+			return s.RemovePermissionFromRole(1, 1, 2)
+		}},
+		{"parent set", 4, "posts", "read", false, true, func(s *RoleService) error {
+			_, err := s.SetRoleParent(1, 4, parent(1))
+			return err
+		}},
+		{"parent removed", 2, "posts", "read", true, false, func(s *RoleService) error {
+			_, err := s.SetRoleParent(1, 2, nil)
+			return err
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newFakeRoleRepository()
+			service := newTestRoleService(repo, time.Minute)
+			check := func(want bool) {
+				t.Helper()
+				got, err := service.CheckPermission(tc.userID, tc.resource, tc.action)
+				if err != nil || got != want {
+					t.Fatalf("CheckPermission(%d, %s:%s) = %v, %v; want %v", tc.userID, tc.resource, tc.action, got, err, want)
+				}
+			}
+
+			check(tc.before)
+			check(tc.before)
+			if repo.lookups != 1 {
+				t.Fatalf("%d permission lookups for two checks, want 1", repo.lookups)
+			}
+
+			if err := tc.change(service); err != nil {
+				t.Fatalf("change: %v", err)
+			}
+			check(tc.after)
+			if repo.lookups != 2 {
+				t.Fatalf("%d permission lookups after the change, want 2", repo.lookups)
+			}
+		})
+	}
+}
+
+func TestPermissionCache(t *testing.T) {
+	permissions := map[string]struct{}{"posts:read": {}}
+
+	t.Run("stale generation", func(t *testing.T) {
+		cache := newPermissionCache(time.Minute)
+		_, generation, _ := cache.get(1)
+		cache.invalidate(2)
+		cache.set(1, permissions, generation)
+		if _, _, ok := cache.get(1); ok {
+			t.Fatal("permissions loaded before an invalidation were cached")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cache := newPermissionCache(0)
+		_, generation, _ := cache.get(1)
+		cache.set(1, permissions, generation)
+		if _, _, ok := cache.get(1); ok {
+			t.Fatal("permissions were cached with a zero ttl")
+		}
+	})
+
+	t.Run("expired entries are swept", func(t *testing.T) {
+		cache := newPermissionCache(time.Minute)
+		expired := time.Now().Add(-time.Second)
+		for userID := uint(1); userID <= 3; userID++ {
+			cache.entries[userID] = permissionCacheEntry{permissions: permissions, expiresAt: expired}
+		}
+		cache.entries[4] = permissionCacheEntry{permissions: permissions, expiresAt: time.Now().Add(time.Minute)}
+
+		_, generation, _ := cache.get(5)
+		cache.set(5, permissions, generation)
+		if len(cache.entries) != 2 {
+			t.Fatalf("%d cache entries after the sweep, want 2", len(cache.entries))
+		}
+		for _, userID := range []uint{4, 5} {
+			if _, _, ok := cache.get(userID); !ok {
+				t.Errorf("permissions of user %d were swept", userID)
+			}
+		}
+
+		// The next sweep waits for another ttl
+		cache.entries[1] = permissionCacheEntry{permissions: permissions, expiresAt: expired}
+		cache.set(6, permissions, generation)
+		if _, ok := cache.entries[1]; !ok {
+			t.Fatal("expired entries were swept again within the ttl")
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"bad_boyes/internal/fieldcrypt"
+	"bad_boyes/internal/models"
+	"bad_boyes/internal/repository"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+type SubjectService struct {
+	subjectRepo *repository.SubjectRepository
+	postRepo    *repository.PostRepository
+	policy      *Policy
+	redactor    *redactor
+}
+
+func NewSubjectService(subjectRepo *repository.SubjectRepository, postRepo *repository.PostRepository, policy *Policy) *SubjectService {
+	return &SubjectService{
+		subjectRepo: subjectRepo,
+		postRepo:    postRepo,
+		policy:      policy,
+		redactor:    newRedactor(policy),
+	}
+}
+
+// GetSubjectProfile returns a subject with the posts about them that the
+// user can see, and the number and date range of those incidents. A subject
+// without posts the user can see is reported as not found, so profiles do
+// not reveal subjects only known from private posts.
+func (s *SubjectService) GetSubjectProfile(userID uint, id uint) (*models.SubjectProfile, error) {
+	if err := s.policy.Authorize(userID, "subjects", "read"); err != nil {
+		return nil, err
+	}
+
+	subject, err := s.subjectRepo.GetSubject(id)
+	if err != nil {
+		return nil, err
+	}
+
+	includePrivate := s.policy.Authorize(userID, "posts", "read_private") == nil
+	posts, err := s.postRepo.ListSubjectPosts(subject.ID, &userID, includePrivate)
+	if err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	viewer := s.redactor.viewer(userID)
+	viewer.subject(subject)
+	viewer.posts(posts)
+
+	profile := &models.SubjectProfile{
+		Subject:       *subject,
+		IncidentCount: len(posts),
+		Posts:         posts,
+	}
+	for i := range posts {
+		date := &posts[i].IncidentDate
+		if profile.FirstIncident == nil || time.Time(*date).Before(time.Time(*profile.FirstIncident)) {
+			profile.FirstIncident = date
+		}
+		if profile.LastIncident == nil || time.Time(*date).After(time.Time(*profile.LastIncident)) {
+			profile.LastIncident = date
+		}
+	}
+	return profile, nil
+}
+
+// linkSubject points a post at the subject matching its contact name and
+// normalized mobile number. Posts without a valid number have no subject.
+func linkSubject(subjectRepo *repository.SubjectRepository, post *models.Post) error {
+	post.SubjectID = nil
+	if post.MobileNumberE164 == nil {
+		return nil
+	}
+	name := strings.Join(strings.Fields(post.ContactName), " ")
+	key := subjectNameKey(name)
+	if key == "" {
+		return nil
+	}
+
+	phoneIndex, err := fieldcrypt.BlindIndex(models.SubjectPhoneIndex, *post.MobileNumberE164)
+	if err != nil {
+		return err
+	}
+	nameIndex, err := fieldcrypt.BlindIndex(models.SubjectNameIndex, key)
+	if err != nil {
+		return err
+	}
+
+	subject, err := subjectRepo.FindOrCreateSubject(&models.Subject{
+		Phone:      *post.MobileNumberE164,
+		PhoneIndex: phoneIndex,
+		Name:       name,
+		NameKey:    nameIndex,
+	})
+	if err != nil {
+		return err
+	}
+	post.SubjectID = &subject.ID
+	return nil
+}
+
+// subjectNameKey lower-cases a name and drops everything but letters,
+// digits and single spaces, so "O'Brien, John" and "o brien john" differ
+// only where the letters do.
+func subjectNameKey(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+			return unicode.ToLower(r)
+		case unicode.IsSpace(r), r == ',', r == '-':
+			return ' '
+		}
+		return -1
+	}, name)
+	return strings.Join(strings.Fields(name), " ")
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"bad_boyes/internal/models"
+	"bad_boyes/internal/repository"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCategoryNotFound      = errors.New("category not found")
+	ErrCategoryAlreadyExists = errors.New("category already exists")
+	ErrInvalidCategorySlug   = errors.New("category slug must contain only lowercase letters, digits and single '-' separators")
+	ErrInvalidTag            = errors.New("tags must contain letters or digits and be at most 50 characters long")
+)
+
+const (
+	maxTagsPerPost     = 10
+	maxTagLength       = 50
+	defaultPopularTags = 20
+	maxPopularTags     = 100
+	popularTagsWindow  = 30 * 24 * time.Hour
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type TaxonomyService struct {
+	taxonomyRepo *repository.TaxonomyRepository
+	auditRepo    *repository.AuditRepository
+}
+
+func NewTaxonomyService(taxonomyRepo *repository.TaxonomyRepository, auditRepo *repository.AuditRepository) *TaxonomyService {
+	return &TaxonomyService{
+		taxonomyRepo: taxonomyRepo,
+		auditRepo:    auditRepo,
+	}
+}
+
+func (s *TaxonomyService) ListCategories() ([]models.Category, error) {
+	return s.taxonomyRepo.ListCategories()
+}
+
+func (s *TaxonomyService) CreateCategory(actorID uint, req models.CreateCategoryRequest) (*models.Category, error) {
+	if len(req.Slug) > 50 || !slugPattern.MatchString(req.Slug) {
+		return nil, ErrInvalidCategorySlug
+	}
+
+	category := &models.Category{
+		Slug:        req.Slug,
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+	}
+	if err := s.taxonomyRepo.CreateCategory(category); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrCategoryAlreadyExists
+		}
+		return nil, err
+	}
+
+	s.logTaxonomyEvent(actorID, "create_category", category.ID, nil, models.JSON{
+		"slug":        category.Slug,
+		"name":        category.Name,
+		"description": category.Description,
+	})
+	return category, nil
+}
+
+// UpdateCategory renames a category. The slug is kept since clients filter
+// by it.
+func (s *TaxonomyService) UpdateCategory(actorID, categoryID uint, req models.UpdateCategoryRequest) (*models.Category, error) {
+	category, err := s.getCategory(categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	oldValues := models.JSON{"name": category.Name, "description": category.Description}
+	category.Name = strings.TrimSpace(req.Name)
+	category.Description = req.Description
+	if err := s.taxonomyRepo.UpdateCategory(category); err != nil {
+		return nil, err
+	}
+
+	s.logTaxonomyEvent(actorID, "update_category", category.ID, oldValues, models.JSON{
+		"name":        category.Name,
+		"description": category.Description,
+	})
+	return category, nil
+}
+
+func (s *TaxonomyService) DeleteCategory(actorID, categoryID uint) error {
+	category, err := s.getCategory(categoryID)
+	if err != nil {
+		return err
+	}
+	if err := s.taxonomyRepo.DeleteCategory(category.ID); err != nil {
+		return err
+	}
+
+	s.logTaxonomyEvent(actorID, "delete_category", category.ID, models.JSON{
+		"slug": category.Slug,
+		"name": category.Name,
+	}, nil)
+	return nil
+}
+
+// PopularTags returns the tags used most on public posts in the last 30 days.
+func (s *TaxonomyService) PopularTags(limit int) ([]models.TagUsage, error) {
+	if limit < 1 {
+		limit = defaultPopularTags
+	}
+	if limit > maxPopularTags {
+		limit = maxPopularTags
+	}
+	return s.taxonomyRepo.PopularTags(time.Now().Add(-popularTagsWindow), limit)
+}
+
+func (s *TaxonomyService) getCategory(id uint) (*models.Category, error) {
+	category, err := s.taxonomyRepo.GetCategory(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCategoryNotFound
+	}
+	return category, err
+}
+
+// normalizeTags lower-cases tags, drops a leading '#', joins words with '-'
+// and removes duplicates, so "#Car Theft" and "car-theft" are the same tag.
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) > maxTagsPerPost {
+		return nil, ErrInvalidTag
+	}
+	return normalized, nil
+}
+
+func normalizeTag(tag string) (string, error) {
+	tag = strings.TrimPrefix(strings.TrimSpace(strings.ToLower(tag)), "#")
+	tag = strings.Join(strings.Fields(tag), "-")
+	if len([]rune(tag)) > maxTagLength || strings.ContainsAny(tag, "\"'`<>;,") ||
+		strings.IndexFunc(tag, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) < 0 {
+		return "", ErrInvalidTag
+	}
+	return tag, nil
+}
+
+func (s *TaxonomyService) logTaxonomyEvent(actorID uint, action string, recordID uint, oldValues, newValues models.JSON) {
+	auditLog := &models.AuditLog{
+		UserID:    &actorID,
+		Action:    action,
+		TableName: "categories",
+		RecordID:  recordID,
+		OldValues: oldValues,
+		NewValues: newValues,
+	}
+	if err := s.auditRepo.CreateLog(auditLog); err != nil {
+		log.Printf("Failed to create audit log for %s: %v", action, err)
+	}
+}
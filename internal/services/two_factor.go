@@ -0,0 +1,460 @@
+package services
+
+import (
+	"bad_boyes/internal/models"
+	"bad_boyes/internal/totp"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor authentication is not enrolled")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor authentication code")
+	ErrInvalidMFAToken         = errors.New("invalid or expired MFA challenge token")
+	ErrTooManyMFAAttempts      = errors.New("too many wrong two-factor codes, try again later")
+)
+
+const (
+	defaultMFAChallengeTTL    = 5 * time.Minute
+	defaultMFAIssuer          = "Bad Boyes"
+	defaultMFAMaxAttempts     = 5
+	defaultMFAMaxUserFailures = 10
+	defaultMFALockoutWindow   = 15 * time.Minute
+	recoveryCodeCount         = 10
+)
+
+// recoveryCodePattern is the format of generated recovery codes: two groups
+// of five base32 characters, optionally separated by a dash.
+var recoveryCodePattern = regexp.MustCompile(`^[a-z2-7]{5}-?[a-z2-7]{5}$`)
+
+type TwoFactorEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TwoFactorConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// EnrollTwoFactor generates a new TOTP secret for the user. Two-factor is not
+// enforced until the enrollment is confirmed with ConfirmTwoFactor.
+func (s *AuthService) EnrollTwoFactor(userID uint) (*TwoFactorEnrollmentResponse, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		log.Printf("Database error during 2FA enrollment: %v", err)
+		return nil, ErrDatabaseError
+	}
+
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Printf("Error generating TOTP secret: %v", err)
+		return nil, errors.New("error generating two-factor secret")
+	}
+
+	if err := s.userRepo.SetTOTPSecret(user.ID, secret); err != nil {
+		log.Printf("Error storing TOTP secret for user %d: %v", user.ID, err)
+		return nil, ErrDatabaseError
+	}
+
+	log.Printf("2FA enrollment started for user %d", user.ID)
+	return &TwoFactorEnrollmentResponse{
+		Secret: secret,
+		URI:    totp.URI(s.mfaIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables two-factor once the user proves the authenticator
+// app is set up, and returns a fresh set of recovery codes. The codes are
+// only ever shown here.
+func (s *AuthService) ConfirmTwoFactor(userID uint, code string) (*TwoFactorConfirmResponse, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		log.Printf("Database error during 2FA confirmation: %v", err)
+		return nil, ErrDatabaseError
+	}
+
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	if err := s.verifyAccountCode(user, code, s.verifyTOTP); err != nil {
+		return nil, err
+	}
+
+	codes, hashed, err := generateRecoveryCodes(user.ID)
+	if err != nil {
+		log.Printf("Error generating recovery codes: %v", err)
+		return nil, errors.New("error generating recovery codes")
+	}
+	if err := s.userRepo.ReplaceRecoveryCodes(user.ID, hashed); err != nil {
+		log.Printf("Error storing recovery codes for user %d: %v", user.ID, err)
+		return nil, ErrDatabaseError
+	}
+
+	if err := s.userRepo.EnableTOTP(user.ID, time.Now()); err != nil {
+		log.Printf("Error enabling 2FA for user %d: %v", user.ID, err)
+		return nil, ErrDatabaseError
+	}
+
+	s.logAuthEvent(user.ID, "enable_2fa", "users", user.ID, nil)
+	log.Printf("2FA enabled for user %d", user.ID)
+	return &TwoFactorConfirmResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor turns two-factor off after checking a current TOTP or
+// recovery code, and deletes the remaining recovery codes.
+func (s *AuthService) DisableTwoFactor(userID uint, code string) error {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return ErrDatabaseError
+	}
+
+	if user.TOTPEnabledAt == nil {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	if err := s.verifyAccountCode(user, code, s.verifySecondFactor); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.DisableTOTP(user.ID); err != nil {
+		log.Printf("Error disabling 2FA for user %d: %v", user.ID, err)
+		return ErrDatabaseError
+	}
+
+	s.logAuthEvent(user.ID, "disable_2fa", "users", user.ID, nil)
+	log.Printf("2FA disabled for user %d", user.ID)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the user after
+// checking a current TOTP code.
+func (s *AuthService) RegenerateRecoveryCodes(userID uint, code string) (*TwoFactorConfirmResponse, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, ErrDatabaseError
+	}
+
+	if user.TOTPEnabledAt == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	if err := s.verifyAccountCode(user, code, s.verifyTOTP); err != nil {
+		return nil, err
+	}
+
+	codes, hashed, err := generateRecoveryCodes(user.ID)
+	if err != nil {
+		log.Printf("Error generating recovery codes: %v", err)
+		return nil, errors.New("error generating recovery codes")
+	}
+	if err := s.userRepo.ReplaceRecoveryCodes(user.ID, hashed); err != nil {
+		log.Printf("Error storing recovery codes for user %d: %v", user.ID, err)
+		return nil, ErrDatabaseError
+	}
+
+	s.logAuthEvent(user.ID, "regenerate_recovery_codes", "users", user.ID, nil)
+	return &TwoFactorConfirmResponse{RecoveryCodes: codes}, nil
+}
+
+// CompleteMFALogin finishes a two-step login by checking the second factor
+// against the challenge token returned by Login. Each challenge token can
+// only be redeemed once, and is revoked after too many wrong codes. Users
+// who enter too many wrong codes over several challenges are locked out
+// for a while.
+func (s *AuthService) CompleteMFALogin(mfaToken, code string) (*LoginResponse, error) {
+	userID, jti, expiresAt, err := s.parseMFAChallenge(mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.tokenRepo.IsAccessTokenRevoked(jti)
+	if err != nil {
+		return nil, ErrDatabaseError
+	}
+	if revoked {
+		return nil, ErrInvalidMFAToken
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidMFAToken
+		}
+		log.Printf("Database error during MFA login: %v", err)
+		return nil, ErrDatabaseError
+	}
+	if user.TOTPEnabledAt == nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	if err := s.checkMFALockout(user.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifySecondFactor(user, code); err != nil {
+		log.Printf("MFA login failed for user %d: %v", user.ID, err)
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if err := s.recordMFAFailure(user.ID, jti, expiresAt); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.tokenRepo.RevokeAccessToken(&models.RevokedToken{
+		JTI:       jti,
+		UserID:    user.ID,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		log.Printf("Failed to consume MFA challenge %s: %v", jti, err)
+		return nil, ErrDatabaseError
+	}
+
+	if err := s.tokenRepo.ClearMFAFailures(user.ID); err != nil {
+		log.Printf("Failed to clear MFA failures of user %d: %v", user.ID, err)
+	}
+
+	response, err := s.startSession(user, true)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("MFA login successful for user: %s", user.Email)
+	return response, nil
+}
+
+// verifyAccountCode checks the code a signed-in user enters to change their
+// two-factor settings. Wrong codes count towards the same per-user limit as
+// MFA logins, so these endpoints cannot be used to guess codes either.
+func (s *AuthService) verifyAccountCode(user *models.User, code string, verify func(*models.User, string) error) error {
+	if err := s.checkMFALockout(user.ID); err != nil {
+		return err
+	}
+	if err := verify(user, code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if err := s.recordMFAFailure(user.ID, "", time.Time{}); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// checkMFALockout refuses second factor checks for a user who entered too
+// many wrong codes within the lockout window.
+func (s *AuthService) checkMFALockout(userID uint) error {
+	failures, err := s.tokenRepo.CountUserMFAFailures(userID, time.Now().Add(-s.mfaLockoutWindow))
+	if err != nil {
+		return ErrDatabaseError
+	}
+	if failures >= s.mfaMaxUserFailures {
+		log.Printf("Second factor checks blocked for user %d after %d wrong codes", userID, failures)
+		return ErrTooManyMFAAttempts
+	}
+	return nil
+}
+
+// recordMFAFailure counts a wrong code against a challenge and revokes the
+// challenge once it has used up its attempts. Wrong codes entered outside a
+// login have no challenge and only count against the user.
+func (s *AuthService) recordMFAFailure(userID uint, jti string, expiresAt time.Time) error {
+	if err := s.tokenRepo.RecordMFAFailure(&models.MFAFailure{UserID: userID, ChallengeID: jti}); err != nil {
+		log.Printf("Failed to record MFA failure for user %d: %v", userID, err)
+		return ErrDatabaseError
+	}
+	if jti == "" {
+		return nil
+	}
+
+	attempts, err := s.tokenRepo.CountMFAFailures(jti)
+	if err != nil {
+		return ErrDatabaseError
+	}
+	if attempts < s.mfaMaxAttempts {
+		return nil
+	}
+
+	log.Printf("Revoking MFA challenge of user %d after %d wrong codes", userID, attempts)
+	if err := s.tokenRepo.RevokeAccessToken(&models.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		log.Printf("Failed to revoke MFA challenge %s: %v", jti, err)
+		return ErrDatabaseError
+	}
+	return nil
+}
+
+// MFARequired reports whether the user must have signed in with a second
+// factor, which is the case for admins when REQUIRE_ADMIN_2FA is enabled.
+func (s *AuthService) MFARequired(userID uint) (bool, error) {
+	if !s.requireAdminMFA {
+		return false, nil
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, ErrUserNotFound
+		}
+		return false, ErrDatabaseError
+	}
+	return hasRole(user, "admin"), nil
+}
+
+func (s *AuthService) startMFAChallenge(user *models.User) (*LoginResponse, error) {
+	jti, err := generateRandomToken(16)
+	if err != nil {
+		log.Printf("Error generating challenge ID: %v", err)
+		return nil, errors.New("error generating authentication token")
+	}
+
+	expiresAt := time.Now().Add(s.mfaChallengeTTL)
+	token, err := s.signToken(jwt.MapClaims{
+		"jti":       jti,
+		"token_use": TokenUseMFAChallenge,
+		"user_id":   user.ID,
+		"exp":       expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		UserID:      user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		Name:        user.Name,
+		ExpiresAt:   expiresAt,
+		MFARequired: true,
+		MFAToken:    token,
+	}, nil
+}
+
+func (s *AuthService) parseMFAChallenge(tokenString string) (uint, string, time.Time, error) {
+	claims, err := s.ParseToken(tokenString)
+	if err != nil {
+		return 0, "", time.Time{}, ErrInvalidMFAToken
+	}
+
+	if use, _ := claims["token_use"].(string); use != TokenUseMFAChallenge {
+		return 0, "", time.Time{}, ErrInvalidMFAToken
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, "", time.Time{}, ErrInvalidMFAToken
+	}
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return 0, "", time.Time{}, ErrInvalidMFAToken
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return 0, "", time.Time{}, ErrInvalidMFAToken
+	}
+
+	return uint(userID), jti, exp.Time, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused
+// recovery code. Anything that is neither a six-digit code nor in the
+// recovery code format is rejected without a lookup.
+func (s *AuthService) verifySecondFactor(user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		return s.verifyTOTP(user, code)
+	}
+	if !recoveryCodePattern.MatchString(strings.ToLower(code)) {
+		return ErrInvalidTwoFactorCode
+	}
+
+	if err := s.userRepo.UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(code))); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidTwoFactorCode
+		}
+		return ErrDatabaseError
+	}
+
+	s.logAuthEvent(user.ID, "use_recovery_code", "users", user.ID, nil)
+	return nil
+}
+
+func (s *AuthService) verifyTOTP(user *models.User, code string) error {
+	step, ok := totp.Validate(code, user.TOTPSecret, time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	// Each time step may only be used once
+	if err := s.userRepo.UseTOTPStep(user.ID, step); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidTwoFactorCode
+		}
+		return ErrDatabaseError
+	}
+	return nil
+}
+
+// generateRecoveryCodes returns the plaintext codes to show to the user and
+// their hashed records for storage.
+func generateRecoveryCodes(userID uint) ([]string, []models.RecoveryCode, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashed := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := totp.GenerateSecret()
+		if err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(raw[:5] + "-" + raw[5:10])
+		codes = append(codes, code)
+		hashed = append(hashed, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hashToken(normalizeRecoveryCode(code)),
+		})
+	}
+	return codes, hashed, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, " ", "")
+	return strings.ReplaceAll(code, "-", "")
+}
+
+func hasRole(user *models.User, name string) bool {
+	for _, role := range user.Roles {
+		if role.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"bad_boyes/internal/models"
+	"bad_boyes/internal/totp"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeUserRepository serves a single user from memory. Recovery codes are
+// never found, so only TOTP codes are accepted.
+type fakeUserRepository struct {
+	userRepository
+
+	user      models.User
+	usedSteps map[int64]bool
+}
+
+func (r *fakeUserRepository) GetUserByID(id uint) (*models.User, error) {
+	if id != r.user.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	user := r.user
+	return &user, nil
+}
+
+func (r *fakeUserRepository) UseTOTPStep(userID uint, step int64) error {
+	if r.usedSteps[step] {
+		return gorm.ErrRecordNotFound
+	}
+	r.usedSteps[step] = true
+	return nil
+}
+
+func (r *fakeUserRepository) UseRecoveryCode(userID uint, codeHash string) error {
+	return gorm.ErrRecordNotFound
+}
+
+// fakeTokenRepository keeps failed MFA attempts in memory.
+type fakeTokenRepository struct {
+	tokenRepository
+
+	failures []models.MFAFailure
+}
+
+func (r *fakeTokenRepository) RecordMFAFailure(failure *models.MFAFailure) error {
+	failure.CreatedAt = time.Now()
+	r.failures = append(r.failures, *failure)
+	return nil
+}
+
+func (r *fakeTokenRepository) CountUserMFAFailures(userID uint, since time.Time) (int64, error) {
+	var count int64
+	for _, failure := range r.failures {
+		if failure.UserID == userID && !failure.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func TestTwoFactorSettingsLockout(t *testing.T) {
+	const maxFailures = 3
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabledAt := time.Now().Add(-time.Hour)
+
+	for _, tc := range []struct {
+		name      string
+		enabledAt *time.Time
+		check     func(s *AuthService, code string) error
+	}{
+		{"confirm", nil, func(s *AuthService, code string) error {
+			_, err := s.ConfirmTwoFactor(1, code)
+			return err
+		}},
+		{"disable", &enabledAt, func(s *AuthService, code string) error {
+			return s.DisableTwoFactor(1, code)
+		}},
+		{"regenerate recovery codes", &enabledAt, func(s *AuthService, code string) error {
+			_, err := s.RegenerateRecoveryCodes(1, code)
+			return err
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenRepo := &fakeTokenRepository{}
+			s := &AuthService{
+				userRepo: &fakeUserRepository{
+					user:      models.User{ID: 1, TOTPSecret: secret, TOTPEnabledAt: tc.enabledAt},
+					usedSteps: make(map[int64]bool),
+				},
+				tokenRepo:          tokenRepo,
+				mfaMaxAttempts:     defaultMFAMaxAttempts,
+				mfaMaxUserFailures: maxFailures,
+				mfaLockoutWindow:   time.Minute,
+			}
+
+			for _, code := range []string{"000000", "abcde-fghij", "not a code"} {
+				if err := tc.check(s, code); !errors.Is(err, ErrInvalidTwoFactorCode) {
+					t.Fatalf("code %q: err = %v, want ErrInvalidTwoFactorCode", code, err)
+				}
+			}
+			if len(tokenRepo.failures) != maxFailures {
+				t.Fatalf("recorded %d failures, want %d", len(tokenRepo.failures), maxFailures)
+			}
+			for _, failure := range tokenRepo.failures {
+				if failure.UserID != 1 || failure.ChallengeID != "" {
+					t.Errorf("recorded failure %+v, want one for user 1 without a challenge", failure)
+				}
+			}
+
+			code, err := totp.GenerateCode(secret, totp.Step(time.Now()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tc.check(s, code); !errors.Is(err, ErrTooManyMFAAttempts) {
+				t.Fatalf("correct code after %d failures: err = %v, want ErrTooManyMFAAttempts", maxFailures, err)
+			}
+			if len(tokenRepo.failures) != maxFailures {
+				t.Fatalf("a refused check was recorded as a failure")
+			}
+		})
+	}
+}
+
+// TestTwoFactorSettingsShareLoginLimit checks that wrong codes entered for
+// MFA login challenges also block changes to the two-factor settings, and
+// that failures outside the lockout window are ignored.
+func TestTwoFactorSettingsShareLoginLimit(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		age  time.Duration
+		want error
+	}{
+		{"recent login failures", 0, ErrTooManyMFAAttempts},
+		{"expired login failures", 2 * time.Minute, ErrInvalidTwoFactorCode},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenRepo := &fakeTokenRepository{}
+			for i := 0; i < 2; i++ {
+				tokenRepo.failures = append(tokenRepo.failures, models.MFAFailure{
+					UserID:      1,
+					ChallengeID: "challenge",
+					CreatedAt:   time.Now().Add(-tc.age),
+				})
+			}
+			enabledAt := time.Now()
+			s := &AuthService{
+				userRepo: &fakeUserRepository{
+					user:      models.User{ID: 1, TOTPSecret: "JBSWY3DPEHPK3PXP", TOTPEnabledAt: &enabledAt},
+					usedSteps: make(map[int64]bool),
+				},
+				tokenRepo:          tokenRepo,
+				mfaMaxUserFailures: 2,
+				mfaLockoutWindow:   time.Minute,
+			}
+			if err := s.DisableTwoFactor(1, "000000"); !errors.Is(err, tc.want) {
+				t.Fatalf("DisableTwoFactor err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+// Package signing manages the keys used to sign and verify JWTs. It supports
+// RS256 and EdDSA keys loaded from a directory, identified by the "kid"
+// header, so keys can be rotated while tokens signed with older keys remain
+// valid. A shared HMAC secret is still supported for single-service setups.
+package signing
+
+import (
+	"bad_boyes/internal/config"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrNoSigningKey = errors.New("no JWT signing key is configured")
+	ErrUnknownKey   = errors.New("token was signed with an unknown key")
+)
+
+// Key is a single signing or verification key.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	// signKey is nil for keys that are only kept to verify older tokens
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// KeyStore holds the active signing key and every key that is still accepted
+// for verification.
+type KeyStore struct {
+	signing *Key
+	keys    map[string]*Key
+
+	// hmac verifies legacy tokens that carry no kid header, until
+	// hmacUntil when that is set
+	hmac      *Key
+	hmacUntil time.Time
+}
+
+// NewHMACKeyStore returns a store that signs and verifies with a shared
+// HS256 secret.
+func NewHMACKeyStore(secret string) *KeyStore {
+	key := &Key{
+		Method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+	return &KeyStore{signing: key, keys: map[string]*Key{}, hmac: key}
+}
+
+// LoadKeyStore reads keys from dir. Every "<kid>.pem" file holding an RSA or
+// Ed25519 private key can sign and verify; "<kid>.pub.pem" files holding a
+// public key only verify, which is how retired keys are kept around. The key
+// named signingKID signs new tokens; when empty, the private key with the
+// greatest kid in lexical order is used, so date-based kids rotate naturally.
+func LoadKeyStore(dir, signingKID string) (*KeyStore, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	store := &KeyStore{keys: map[string]*Key{}}
+	var signable []string
+	for _, file := range files {
+		name := filepath.Base(file)
+		kid := strings.TrimSuffix(strings.TrimSuffix(name, ".pem"), ".pub")
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseKey(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", name, err)
+		}
+
+		if existing, ok := store.keys[kid]; ok && existing.signKey != nil {
+			// Keep the private key when both files are present
+			continue
+		}
+		store.keys[kid] = key
+		if key.signKey != nil {
+			signable = append(signable, kid)
+		}
+	}
+
+	if signingKID == "" {
+		if len(signable) == 0 {
+			return nil, ErrNoSigningKey
+		}
+		sort.Strings(signable)
+		signingKID = signable[len(signable)-1]
+	}
+
+	key, ok := store.keys[signingKID]
+	if !ok || key.signKey == nil {
+		return nil, fmt.Errorf("signing key %q not found in %s: %w", signingKID, dir, ErrNoSigningKey)
+	}
+	store.signing = key
+
+	return store, nil
+}
+
+// NewKeyStoreFromEnv loads keys from JWT_KEYS_DIR (signing with
+// JWT_SIGNING_KID) when set. JWT_SECRET is used to sign when no keys directory
+// is configured. With a keys directory, HS256 tokens issued before the switch
+// are only accepted when JWT_ACCEPT_LEGACY_HS256 is enabled, and only until
+// the RFC 3339 time in JWT_LEGACY_HS256_UNTIL.
+func NewKeyStoreFromEnv() (*KeyStore, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	secret := os.Getenv("JWT_SECRET")
+
+	if dir == "" {
+		if secret == "" {
+			return nil, ErrNoSigningKey
+		}
+		return NewHMACKeyStore(secret), nil
+	}
+
+	store, err := LoadKeyStore(dir, os.Getenv("JWT_SIGNING_KID"))
+	if err != nil {
+		return nil, err
+	}
+	if !config.GetBool("JWT_ACCEPT_LEGACY_HS256", false) {
+		return store, nil
+	}
+
+	if secret == "" {
+		return nil, errors.New("JWT_ACCEPT_LEGACY_HS256 is enabled but JWT_SECRET is not set")
+	}
+	until, err := time.Parse(time.RFC3339, os.Getenv("JWT_LEGACY_HS256_UNTIL"))
+	if err != nil {
+		return nil, fmt.Errorf("JWT_ACCEPT_LEGACY_HS256 needs JWT_LEGACY_HS256_UNTIL as an RFC 3339 time: %w", err)
+	}
+	store.hmac = NewHMACKeyStore(secret).hmac
+	store.hmacUntil = until
+	return store, nil
+}
+
+// Sign signs the claims with the active signing key, setting the kid header
+// for asymmetric keys.
+func (s *KeyStore) Sign(claims jwt.Claims) (string, error) {
+	if s.signing == nil {
+		return "", ErrNoSigningKey
+	}
+
+	token := jwt.NewWithClaims(s.signing.Method, claims)
+	if s.signing.ID != "" {
+		token.Header["kid"] = s.signing.ID
+	}
+	return token.SignedString(s.signing.signKey)
+}
+
+// Parse verifies the token against the key named by its kid header and
+// returns its claims. The token's alg must match the algorithm of that key.
+func (s *KeyStore) Parse(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		key, err := s.lookup(token)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return key.verifyKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
+
+func (s *KeyStore) lookup(token *jwt.Token) (*Key, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if s.hmac == nil || (!s.hmacUntil.IsZero() && time.Now().After(s.hmacUntil)) {
+			return nil, ErrUnknownKey
+		}
+		return s.hmac, nil
+	}
+
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// Ed25519 (RFC 8037)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public halves of all asymmetric verification keys. HMAC
+// secrets are never published.
+func (s *KeyStore) JWKS() JWKS {
+	kids := make([]string, 0, len(s.keys))
+	for kid := range s.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := JWKS{Keys: []JWK{}}
+	for _, kid := range kids {
+		key := s.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func parseKey(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodRS256, signKey: k, verifyKey: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodRS256, verifyKey: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodEdDSA, signKey: k, verifyKey: k.Public().(ed25519.PublicKey)}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodEdDSA, verifyKey: k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+}
@@ -0,0 +1,91 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func writeEd25519Key(t *testing.T, dir, kid string) {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewKeyStoreFromEnvLegacyHS256(t *testing.T) {
+	dir := t.TempDir()
+	writeEd25519Key(t, dir, "2025-06-01")
+	claims := jwt.MapClaims{"user_id": 1, "exp": time.Now().Add(time.Hour).Unix()}
+	legacy, err := NewHMACKeyStore("secrets").Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		accept   string
+		until    string
+		loadErr  bool
+		accepted bool
+	}{
+		{"not enabled", "", "", false, false},
+		{"disabled", "false", time.Now().Add(time.Hour).Format(time.RFC3339), false, false},
+		{"enabled before the cutoff", "true", time.Now().Add(time.Hour).Format(time.RFC3339), false, true},
+		{"enabled after the cutoff", "true", time.Now().Add(-time.Hour).Format(time.RFC3339), false, false},
+		{"enabled without a cutoff", "true", "", true, false},
+		{"enabled with an invalid cutoff", "true", "next week", true, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("JWT_KEYS_DIR", dir)
+			t.Setenv("JWT_SIGNING_KID", "")
+			t.Setenv("JWT_SECRET", "secrets")
+			t.Setenv("JWT_ACCEPT_LEGACY_HS256", tc.accept)
+			t.Setenv("JWT_LEGACY_HS256_UNTIL", tc.until)
+
+			store, err := NewKeyStoreFromEnv()
+			if tc.loadErr {
+				if err == nil {
+					t.Fatal("NewKeyStoreFromEnv succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewKeyStoreFromEnv: %v", err)
+			}
+
+			_, err = store.Parse(legacy)
+			if tc.accepted && err != nil {
+				t.Fatalf("legacy token rejected: %v", err)
+			}
+			if !tc.accepted && !errors.Is(err, ErrUnknownKey) {
+				t.Fatalf("legacy token: err = %v, want ErrUnknownKey", err)
+			}
+
+			signed, err := store.Sign(claims)
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+			if _, err := store.Parse(signed); err != nil {
+				t.Fatalf("token signed with the key directory rejected: %v", err)
+			}
+		})
+	}
+}
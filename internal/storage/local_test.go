@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStoragePath(t *testing.T) {
+	root := t.TempDir()
+	s := &LocalStorage{root: root}
+
+	for _, tc := range []struct {
+		key  string
+		want string
+	}{
+		{"posts/12/photo.jpg", filepath.Join(root, "posts", "12", "photo.jpg")},
+		{"a", filepath.Join(root, "a")},
+		{"posts/12/..photo.jpg", filepath.Join(root, "posts", "12", "..photo.jpg")},
+	} {
+		got, err := s.path(tc.key)
+		if err != nil || got != tc.want {
+			t.Errorf("path(%q) = %q, %v; want %q", tc.key, got, err, tc.want)
+		}
+	}
+
+	for _, key := range []string{
+		"",
+		"/",
+		".",
+		"..",
+		"../secret",
+		"../../etc/passwd",
+		"posts/../../secret",
+		"posts/12/../../../secret",
+		"posts/..",
+		"/etc/passwd",
+		"posts//photo.jpg",
+		"posts/./photo.jpg",
+		"posts/12/",
+		`posts\..\..\secret`,
+		`..\secret`,
+	} {
+		if got, err := s.path(key); err == nil {
+			t.Errorf("path(%q) = %q, want an error", key, got)
+		}
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	root := t.TempDir()
+	s, err := NewLocalStorage(filepath.Join(root, "attachments"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key := "posts/12/photo.jpg"
+
+	if err := s.Put(ctx, key, []byte("data"), "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	reader, err := s.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || string(got) != "data" {
+		t.Fatalf("Get = %q, %v; want %q", got, err, "data")
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(ctx, key); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("Get of a missing key = %v, want ErrObjectNotFound", err)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete of a missing key = %v, want nil", err)
+	}
+
+	if err := s.Put(ctx, "../escaped", []byte("data"), "text/plain"); err == nil {
+		t.Fatal("Put outside the root succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(root, "escaped")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("file written outside the root: %v", err)
+	}
+}
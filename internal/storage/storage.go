@@ -0,0 +1,48 @@
+// Package storage stores uploaded files behind a small interface with a
+// local filesystem driver and an S3-compatible driver.
+package storage
+
+import (
+	"bad_boyes/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrObjectNotFound = errors.New("object not found")
+
+// Storage keeps objects under slash-separated keys such as
+// "posts/12/4f9c2e.jpg". Implementations must be safe for concurrent use.
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewFromEnv builds the driver selected by STORAGE_DRIVER ("local" or "s3").
+// The local driver writes below STORAGE_LOCAL_PATH, defaulting to
+// storages/attachments. The s3 driver is configured through the S3_*
+// variables.
+func NewFromEnv() (Storage, error) {
+	switch os.Getenv("STORAGE_DRIVER") {
+	case "", "local":
+		path := os.Getenv("STORAGE_LOCAL_PATH")
+		if path == "" {
+			path = "storages/attachments"
+		}
+		return NewLocalStorage(path)
+	case "s3":
+		return NewS3Storage(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PathStyle: config.GetBool("S3_PATH_STYLE", true),
+		})
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q", os.Getenv("STORAGE_DRIVER"))
+	}
+}
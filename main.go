@@ -1,12 +1,20 @@
 package main
 
 import (
+	"bad_boyes/internal/config"
+	"bad_boyes/internal/fieldcrypt"
+	"bad_boyes/internal/geo"
 	"bad_boyes/internal/handler"
-	"bad_boyes/internal/middleware"
+	"bad_boyes/internal/mail"
+	"bad_boyes/internal/phone"
 	"bad_boyes/internal/repository"
+	"bad_boyes/internal/routes"
 	"bad_boyes/internal/services"
+	"bad_boyes/internal/signing"
+	"bad_boyes/internal/storage"
 	"log"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -29,49 +37,91 @@ func main() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	// Initialize field encryption for contact details
+	keyRing, err := fieldcrypt.NewKeyRingFromEnv()
+	if err != nil {
+		log.Fatal("Failed to load field encryption keys:", err)
+	}
+	fieldcrypt.Use(keyRing)
+
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	postRepo := repository.NewPostRepository(db)
+	commentRepo := repository.NewCommentRepository(db)
+	attachmentRepo := repository.NewAttachmentRepository(db)
+	taxonomyRepo := repository.NewTaxonomyRepository(db)
+	subjectRepo := repository.NewSubjectRepository(db)
 	auditRepo := repository.NewAuditRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+
+	// Initialize mail sender
+	mailer, err := mail.NewSenderFromEnv()
+	if err != nil {
+		log.Fatal("Failed to initialize mail sender:", err)
+	}
+
+	// Initialize JWT signing keys
+	keyStore, err := signing.NewKeyStoreFromEnv()
+	if err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+
+	// Initialize the email verification token key
+	verificationSecret, err := services.VerificationSecretFromEnv()
+	if err != nil {
+		log.Fatal("Failed to load email verification secret:", err)
+	}
+
+	// Initialize attachment storage
+	fileStorage, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatal("Failed to initialize attachment storage:", err)
+	}
+
+	// Initialize address geocoding (nil when disabled)
+	geocoder, err := geo.NewGeocoderFromEnv()
+	if err != nil {
+		log.Fatal("Failed to initialize geocoder:", err)
+	}
+
+	// Initialize phone number normalization
+	phones, err := phone.NewNormalizerFromEnv()
+	if err != nil {
+		log.Fatal("Failed to initialize phone normalizer:", err)
+	}
 
 	// Initialize services
-	authService := services.NewAuthService(userRepo, auditRepo)
-	postService := services.NewPostService(postRepo, auditRepo)
+	authService := services.NewAuthService(userRepo, auditRepo, tokenRepo, mailer, keyStore, verificationSecret)
+	roleService := services.NewRoleService(roleRepo, auditRepo)
+	policy := services.NewPolicy(roleService)
+	postService := services.NewPostService(postRepo, taxonomyRepo, subjectRepo, auditRepo, policy, geocoder, phones)
+	commentService := services.NewCommentService(commentRepo, postRepo, auditRepo, policy)
+	taxonomyService := services.NewTaxonomyService(taxonomyRepo, auditRepo)
+	subjectService := services.NewSubjectService(subjectRepo, postRepo, policy)
+	attachmentService := services.NewAttachmentService(attachmentRepo, postRepo, auditRepo, policy, fileStorage)
+
+	// Purge trashed posts, removing their stored files first
+	postService.OnPurge(attachmentService.DeletePostFiles)
+	postService.StartPurgeJob(config.GetDuration("POST_PURGE_INTERVAL", time.Hour))
+
+	// Drop expired tokens and failed MFA attempts that no longer count
+	authService.StartTokenCleanupJob(config.GetDuration("TOKEN_CLEANUP_INTERVAL", time.Hour))
 
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(authService)
 	postHandler := handler.NewPostHandler(postService)
+	commentHandler := handler.NewCommentHandler(commentService)
+	attachmentHandler := handler.NewAttachmentHandler(attachmentService)
+	taxonomyHandler := handler.NewTaxonomyHandler(taxonomyService)
+	subjectHandler := handler.NewSubjectHandler(subjectService)
+	roleHandler := handler.NewRoleHandler(roleService)
 
 	// Initialize router
 	r := gin.Default()
 
-	// Public routes
-	r.POST("/register", authHandler.Register)
-	r.POST("/login", authHandler.Login)
-
-	// Protected routes
-	auth := r.Group("/")
-	auth.Use(middleware.AuthMiddleware())
-	{
-		// Post routes
-		auth.POST("/posts", postHandler.CreatePost)
-		auth.GET("/posts", postHandler.ListPosts)
-		auth.GET("/posts/:id", postHandler.GetPost)
-		auth.PUT("/posts/:id", postHandler.UpdatePost)
-		auth.DELETE("/posts/:id", postHandler.DeletePost)
-		auth.GET("/posts/:id/history", postHandler.GetPostHistory)
-
-		// Report routes
-		auth.POST("/posts/:id/report", postHandler.CreateReport)
-
-		// Admin routes
-		admin := auth.Group("/admin")
-		admin.Use(middleware.AdminMiddleware())
-		{
-			admin.PUT("/reports/:id/status", postHandler.UpdateReportStatus)
-			admin.GET("/reports", postHandler.ListReports)
-		}
-	}
+	// Setup all routes in one place
+	routes.SetupRoutes(r, authService, roleService, authHandler, postHandler, commentHandler, attachmentHandler, taxonomyHandler, subjectHandler, roleHandler)
 
 	port := os.Getenv("PORT")
 	if port == "" {
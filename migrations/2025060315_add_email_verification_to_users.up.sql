@@ -0,0 +1,7 @@
+-- Track email verification on users
+ALTER TABLE users
+    ADD COLUMN verified_at TIMESTAMP NULL,
+    ADD COLUMN verification_sent_at TIMESTAMP NULL;
+
+-- Accounts created before verification was introduced are treated as verified
+UPDATE users SET verified_at = created_at WHERE verified_at IS NULL;
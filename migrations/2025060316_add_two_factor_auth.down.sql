@@ -0,0 +1,9 @@
+ALTER TABLE refresh_tokens
+    DROP COLUMN mfa;
+
+DROP TABLE IF EXISTS recovery_codes;
+
+ALTER TABLE users
+    DROP COLUMN totp_last_step,
+    DROP COLUMN totp_enabled_at,
+    DROP COLUMN totp_secret;
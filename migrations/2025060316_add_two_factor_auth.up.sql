@@ -0,0 +1,20 @@
+-- Add TOTP two-factor authentication to users
+ALTER TABLE users
+    ADD COLUMN totp_secret VARCHAR(64) NULL,
+    ADD COLUMN totp_enabled_at TIMESTAMP NULL,
+    ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+-- Create recovery_codes table for single-use 2FA fallback codes
+CREATE TABLE recovery_codes (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    user_id BIGINT NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_recovery_codes_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Remember whether a token family was started with a second factor
+ALTER TABLE refresh_tokens
+    ADD COLUMN mfa BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,54 @@
+-- Create permissions table
+CREATE TABLE permissions (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT,
+    resource VARCHAR(50) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_permissions_resource_action (resource, action)
+);
+
+-- Create role_permissions table for many-to-many relationship
+CREATE TABLE role_permissions (
+    role_id BIGINT NOT NULL,
+    permission_id BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (role_id, permission_id),
+    FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+);
+
+-- Seed default roles
+INSERT IGNORE INTO roles (name, description) VALUES
+    ('user', 'Regular member who can post and report incidents'),
+    ('moderator', 'Reviews reported posts'),
+    ('admin', 'Full access to the application');
+
+-- Seed default permission catalogue
+INSERT IGNORE INTO permissions (name, description, resource, action) VALUES
+    ('posts:create', 'Create posts', 'posts', 'create'),
+    ('posts:read', 'View and list posts', 'posts', 'read'),
+    ('posts:update', 'Update posts', 'posts', 'update'),
+    ('posts:delete', 'Delete posts', 'posts', 'delete'),
+    ('posts:history', 'View the edit history of posts', 'posts', 'history'),
+    ('reports:create', 'Report posts', 'reports', 'create'),
+    ('reports:read', 'List reports', 'reports', 'read'),
+    ('reports:resolve', 'Change the status of reports', 'reports', 'resolve');
+
+-- Grant permissions to roles
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p
+WHERE r.name IN ('user', 'moderator', 'admin')
+  AND p.name IN ('posts:create', 'posts:read', 'posts:update', 'posts:delete', 'posts:history', 'reports:create');
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p
+WHERE r.name IN ('moderator', 'admin')
+  AND p.name IN ('reports:read', 'reports:resolve');
+
+-- Give existing users without a role the default 'user' role
+INSERT IGNORE INTO user_roles (user_id, role_id)
+SELECT u.id, r.id FROM users u JOIN roles r ON r.name = 'user'
+WHERE NOT EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = u.id);
@@ -0,0 +1 @@
+DELETE FROM permissions WHERE name IN ('roles:read', 'roles:manage');
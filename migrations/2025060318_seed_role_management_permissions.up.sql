@@ -0,0 +1,9 @@
+-- Seed permissions for the role management API
+INSERT IGNORE INTO permissions (name, description, resource, action) VALUES
+    ('roles:read', 'View roles, permissions and user role assignments', 'roles', 'read'),
+    ('roles:manage', 'Create and delete roles and permissions and grant or revoke them', 'roles', 'manage');
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p
+WHERE r.name = 'admin'
+  AND p.name IN ('roles:read', 'roles:manage');
@@ -0,0 +1,5 @@
+DELETE FROM permissions WHERE name = '*:*';
+
+ALTER TABLE roles
+    DROP FOREIGN KEY fk_roles_parent_id,
+    DROP COLUMN parent_id;
@@ -0,0 +1,16 @@
+-- Allow roles to inherit the permissions of a parent role
+ALTER TABLE roles
+    ADD COLUMN parent_id BIGINT NULL,
+    ADD CONSTRAINT fk_roles_parent_id FOREIGN KEY (parent_id) REFERENCES roles(id) ON DELETE SET NULL;
+
+-- moderator inherits user, admin inherits moderator
+UPDATE roles r JOIN roles p ON p.name = 'user' SET r.parent_id = p.id WHERE r.name = 'moderator';
+UPDATE roles r JOIN roles p ON p.name = 'moderator' SET r.parent_id = p.id WHERE r.name = 'admin';
+
+-- Wildcard permission granting everything, held by admin
+INSERT IGNORE INTO permissions (name, description, resource, action) VALUES
+    ('*:*', 'All permissions on all resources', '*', '*');
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p
+WHERE r.name = 'admin' AND p.name = '*:*';
@@ -0,0 +1,16 @@
+DELETE FROM permissions WHERE name IN (
+    'posts:update:own', 'posts:update:any',
+    'posts:delete:own', 'posts:delete:any',
+    'posts:history:own', 'posts:history:any',
+    'posts:read_private'
+);
+
+INSERT IGNORE INTO permissions (name, description, resource, action) VALUES
+    ('posts:update', 'Update posts', 'posts', 'update'),
+    ('posts:delete', 'Delete posts', 'posts', 'delete'),
+    ('posts:history', 'View the edit history of posts', 'posts', 'history');
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p
+WHERE r.name IN ('user', 'moderator')
+  AND p.name IN ('posts:update', 'posts:delete', 'posts:history');
@@ -0,0 +1,23 @@
+-- Broad post permissions are replaced by ownership-aware ones; keeping them
+-- would grant the ':any' variants to every user
+DELETE FROM permissions WHERE name IN ('posts:update', 'posts:delete', 'posts:history');
+
+INSERT IGNORE INTO permissions (name, description, resource, action) VALUES
+    ('posts:update:own', 'Update own posts', 'posts', 'update:own'),
+    ('posts:update:any', 'Update any post', 'posts', 'update:any'),
+    ('posts:delete:own', 'Delete own posts', 'posts', 'delete:own'),
+    ('posts:delete:any', 'Delete any post', 'posts', 'delete:any'),
+    ('posts:history:own', 'View the edit history of own posts', 'posts', 'history:own'),
+    ('posts:history:any', 'View the edit history of any post', 'posts', 'history:any'),
+    ('posts:read_private', 'View private posts of other users', 'posts', 'read_private');
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p
+WHERE r.name = 'user'
+  AND p.name IN ('posts:update:own', 'posts:delete:own', 'posts:history:own');
+
+-- moderator inherits the user permissions
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p
+WHERE r.name = 'moderator'
+  AND p.name IN ('posts:update:any', 'posts:delete:any', 'posts:history:any', 'posts:read_private');
@@ -0,0 +1,6 @@
+DELETE FROM permissions WHERE name IN (
+    'comments:read', 'comments:create',
+    'comments:update:own', 'comments:delete:own', 'comments:delete:any'
+);
+
+DROP TABLE IF EXISTS comments;
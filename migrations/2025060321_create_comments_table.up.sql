@@ -0,0 +1,35 @@
+-- Create comments table; replies point at their parent comment
+CREATE TABLE comments (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    post_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    parent_id BIGINT NULL,
+    body TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'visible',
+    edited_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_comments_post_parent (post_id, parent_id, created_at),
+    INDEX idx_comments_parent (parent_id),
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE CASCADE
+);
+
+INSERT IGNORE INTO permissions (name, description, resource, action) VALUES
+    ('comments:read', 'View comments on posts', 'comments', 'read'),
+    ('comments:create', 'Comment on posts', 'comments', 'create'),
+    ('comments:update:own', 'Edit own comments', 'comments', 'update:own'),
+    ('comments:delete:own', 'Delete own comments', 'comments', 'delete:own'),
+    ('comments:delete:any', 'Remove any comment', 'comments', 'delete:any');
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p
+WHERE r.name = 'user'
+  AND p.name IN ('comments:read', 'comments:create', 'comments:update:own', 'comments:delete:own');
+
+-- moderator inherits the user permissions
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p
+WHERE r.name = 'moderator'
+  AND p.name = 'comments:delete:any';
@@ -0,0 +1,2 @@
+-- Full-text index used by GET /posts/search
+ALTER TABLE posts ADD FULLTEXT INDEX ft_posts_search (title, description, address, contact_name);
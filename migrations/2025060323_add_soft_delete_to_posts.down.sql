@@ -0,0 +1,8 @@
+DELETE FROM permissions WHERE name IN ('posts:restore:own', 'posts:restore:any');
+
+-- Posts still in the trash would reappear once the column is gone
+DELETE FROM posts WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE posts
+    DROP INDEX idx_posts_deleted_at,
+    DROP COLUMN deleted_at;
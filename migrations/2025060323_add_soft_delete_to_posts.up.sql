@@ -0,0 +1,14 @@
+-- Posts are moved to the trash instead of being deleted; a purge job removes
+-- them for good after the retention period
+ALTER TABLE posts
+    ADD COLUMN deleted_at TIMESTAMP NULL,
+    ADD INDEX idx_posts_deleted_at (deleted_at);
+
+INSERT IGNORE INTO permissions (name, description, resource, action) VALUES
+    ('posts:restore:own', 'Restore own deleted posts within the grace period', 'posts', 'restore:own'),
+    ('posts:restore:any', 'Restore any deleted post', 'posts', 'restore:any');
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p
+WHERE r.name = 'user'
+  AND p.name = 'posts:restore:own';
@@ -0,0 +1,10 @@
+DELETE FROM permissions WHERE name = 'categories:manage';
+
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
+
+ALTER TABLE posts
+    DROP FOREIGN KEY fk_posts_category,
+    DROP COLUMN category_id;
+
+DROP TABLE IF EXISTS categories;
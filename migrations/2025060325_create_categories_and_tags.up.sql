@@ -0,0 +1,40 @@
+-- Admin-managed categories; a post has at most one
+CREATE TABLE categories (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    slug VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+ALTER TABLE posts
+    ADD COLUMN category_id BIGINT NULL,
+    ADD CONSTRAINT fk_posts_category FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL;
+
+-- Free-form tags, created on first use
+CREATE TABLE tags (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE post_tags (
+    post_id BIGINT NOT NULL,
+    tag_id BIGINT NOT NULL,
+    PRIMARY KEY (post_id, tag_id),
+    INDEX idx_post_tags_tag (tag_id),
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+INSERT IGNORE INTO categories (slug, name, description) VALUES
+    ('fraud', 'Fraud', 'Financial fraud and identity theft'),
+    ('scam', 'Scam', 'Online, phone and marketplace scams'),
+    ('harassment', 'Harassment', 'Harassment, stalking and threats'),
+    ('theft', 'Theft', 'Theft and burglary'),
+    ('assault', 'Assault', 'Physical violence'),
+    ('other', 'Other', 'Incidents that fit no other category');
+
+INSERT IGNORE INTO permissions (name, description, resource, action) VALUES
+    ('categories:manage', 'Create, update and delete post categories', 'categories', 'manage');
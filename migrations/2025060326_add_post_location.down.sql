@@ -0,0 +1,5 @@
+ALTER TABLE posts
+    DROP INDEX idx_posts_location,
+    DROP COLUMN location,
+    DROP COLUMN longitude,
+    DROP COLUMN latitude;
@@ -0,0 +1,13 @@
+-- Incident coordinates. location mirrors them as a POINT (X = longitude,
+-- Y = latitude) so radius and map queries can use a spatial index; posts
+-- without coordinates store POINT(0 0) and are excluded by latitude IS NULL.
+ALTER TABLE posts
+    ADD COLUMN latitude DECIMAL(9,6) NULL,
+    ADD COLUMN longitude DECIMAL(9,6) NULL,
+    ADD COLUMN location POINT NULL;
+
+UPDATE posts SET location = ST_GeomFromText('POINT(0 0)', 0);
+
+ALTER TABLE posts
+    MODIFY location POINT NOT NULL SRID 0,
+    ADD SPATIAL INDEX idx_posts_location (location);
@@ -0,0 +1,7 @@
+DELETE FROM permissions WHERE name = 'subjects:read';
+
+ALTER TABLE posts
+    DROP FOREIGN KEY fk_posts_subject,
+    DROP COLUMN subject_id;
+
+DROP TABLE IF EXISTS subjects;
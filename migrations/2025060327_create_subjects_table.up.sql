@@ -0,0 +1,23 @@
+-- People that posts are about, identified by their E.164 phone number and
+-- normalized name so incidents about the same person can be grouped
+CREATE TABLE subjects (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    phone VARCHAR(16) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    name_key VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY idx_subjects_phone_name (phone, name_key)
+);
+
+ALTER TABLE posts
+    ADD COLUMN subject_id BIGINT NULL,
+    ADD CONSTRAINT fk_posts_subject FOREIGN KEY (subject_id) REFERENCES subjects(id) ON DELETE SET NULL;
+
+INSERT IGNORE INTO permissions (name, description, resource, action) VALUES
+    ('subjects:read', 'View subject profiles', 'subjects', 'read');
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p
+WHERE r.name = 'user'
+  AND p.name = 'subjects:read';
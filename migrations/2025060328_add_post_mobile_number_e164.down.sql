@@ -0,0 +1,3 @@
+ALTER TABLE posts
+    DROP INDEX idx_posts_mobile_number_e164,
+    DROP COLUMN mobile_number_e164;
@@ -0,0 +1,5 @@
+-- E.164 form of posts.mobile_number, which keeps the number as entered.
+-- Existing rows are filled by `go run ./cmd/migrate backfill-phones`.
+ALTER TABLE posts
+    ADD COLUMN mobile_number_e164 VARCHAR(16) NULL AFTER mobile_number,
+    ADD INDEX idx_posts_mobile_number_e164 (mobile_number_e164);
@@ -0,0 +1 @@
+DELETE FROM permissions WHERE name IN ('posts:view_author', 'posts:view_contact');
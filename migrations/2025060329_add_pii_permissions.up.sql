@@ -0,0 +1,10 @@
+-- Without these, other users' anonymous posts hide their author and contact
+-- details are partially masked
+INSERT IGNORE INTO permissions (name, description, resource, action) VALUES
+    ('posts:view_author', 'See the author of anonymous posts', 'posts', 'view_author'),
+    ('posts:view_contact', 'See unmasked contact details on posts', 'posts', 'view_contact');
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p
+WHERE r.name = 'moderator'
+  AND p.name IN ('posts:view_author', 'posts:view_contact');
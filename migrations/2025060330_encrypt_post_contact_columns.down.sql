@@ -0,0 +1,23 @@
+-- Encrypted values do not fit the original columns, so rolling back is only
+-- safe before any rows have been encrypted.
+ALTER TABLE subjects
+    DROP INDEX idx_subjects_phone_bidx_name_key,
+    DROP COLUMN phone_bidx,
+    MODIFY phone VARCHAR(16) NOT NULL,
+    MODIFY name VARCHAR(255) NOT NULL,
+    ADD UNIQUE KEY idx_subjects_phone_name (phone, name_key);
+
+ALTER TABLE post_history
+    MODIFY contact_name VARCHAR(255) NOT NULL,
+    MODIFY mobile_number VARCHAR(20) NOT NULL;
+
+ALTER TABLE posts
+    DROP INDEX ft_posts_search,
+    DROP INDEX idx_posts_mobile_number_e164_bidx,
+    DROP COLUMN mobile_number_e164_bidx,
+    MODIFY contact_name VARCHAR(255) NOT NULL,
+    MODIFY mobile_number VARCHAR(20) NOT NULL,
+    MODIFY mobile_number_e164 VARCHAR(16) NULL,
+    ADD INDEX idx_posts_mobile_number_e164 (mobile_number_e164);
+
+ALTER TABLE posts ADD FULLTEXT INDEX ft_posts_search (title, description, address, contact_name);
@@ -0,0 +1,26 @@
+-- Contact details are stored encrypted (see internal/fieldcrypt), which needs
+-- wider columns. Existing plaintext rows stay readable and are encrypted by
+-- `go run ./cmd/migrate reencrypt`, which also fills the blind indexes.
+ALTER TABLE posts
+    DROP INDEX idx_posts_mobile_number_e164,
+    DROP INDEX ft_posts_search,
+    MODIFY contact_name TEXT NOT NULL,
+    MODIFY mobile_number TEXT NOT NULL,
+    MODIFY mobile_number_e164 TEXT NULL,
+    ADD COLUMN mobile_number_e164_bidx CHAR(64) NULL AFTER mobile_number_e164,
+    ADD INDEX idx_posts_mobile_number_e164_bidx (mobile_number_e164_bidx);
+
+-- Ciphertext cannot be searched, so search covers title and description
+ALTER TABLE posts ADD FULLTEXT INDEX ft_posts_search (title, description);
+
+ALTER TABLE post_history
+    MODIFY contact_name TEXT NOT NULL,
+    MODIFY mobile_number TEXT NOT NULL;
+
+-- name_key becomes a blind index of the normalized name
+ALTER TABLE subjects
+    DROP INDEX idx_subjects_phone_name,
+    MODIFY phone TEXT NOT NULL,
+    MODIFY name TEXT NOT NULL,
+    ADD COLUMN phone_bidx CHAR(64) NULL AFTER phone,
+    ADD UNIQUE KEY idx_subjects_phone_bidx_name_key (phone_bidx, name_key);
@@ -0,0 +1,2 @@
+ALTER TABLE post_history
+    DROP COLUMN changed_fields;
@@ -0,0 +1,3 @@
+-- Record which fields each update changed
+ALTER TABLE post_history
+    ADD COLUMN changed_fields JSON NULL AFTER allow_comments;
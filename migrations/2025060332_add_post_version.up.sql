@@ -0,0 +1,4 @@
+-- Version counter for optimistic concurrency; every update increments it
+-- and it is the basis of the post's ETag.
+ALTER TABLE posts
+    ADD COLUMN version INT UNSIGNED NOT NULL DEFAULT 1 AFTER allow_comments;
@@ -0,0 +1,9 @@
+ALTER TABLE post_history
+    DROP FOREIGN KEY fk_post_history_editor,
+    DROP INDEX uq_post_history_revision,
+    DROP COLUMN longitude,
+    DROP COLUMN latitude,
+    DROP COLUMN tags,
+    DROP COLUMN category_id,
+    DROP COLUMN editor_id,
+    DROP COLUMN revision;
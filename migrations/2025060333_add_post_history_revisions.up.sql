@@ -0,0 +1,13 @@
+-- History rows become snapshots of a post taken before each update. The
+-- snapshot holds revision `revision` of the post (posts.version at the time)
+-- and editor_id is the user whose update replaced it. Rows written before
+-- this migration were taken after their update and keep a NULL revision.
+ALTER TABLE post_history
+    ADD COLUMN revision INT UNSIGNED NULL AFTER post_id,
+    ADD COLUMN editor_id BIGINT NULL AFTER user_id,
+    ADD COLUMN category_id BIGINT NULL AFTER allow_comments,
+    ADD COLUMN tags JSON NULL AFTER category_id,
+    ADD COLUMN latitude DECIMAL(9,6) NULL AFTER tags,
+    ADD COLUMN longitude DECIMAL(9,6) NULL AFTER latitude,
+    ADD UNIQUE KEY uq_post_history_revision (post_id, revision),
+    ADD CONSTRAINT fk_post_history_editor FOREIGN KEY (editor_id) REFERENCES users(id) ON DELETE SET NULL;
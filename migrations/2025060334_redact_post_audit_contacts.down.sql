@@ -0,0 +1,2 @@
+-- Redacted values cannot be restored
+DO 0;
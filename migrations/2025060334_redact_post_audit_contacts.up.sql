@@ -0,0 +1,9 @@
+-- Contact details are encrypted on posts, so audit entries must not keep
+-- them in plaintext. Entries only record that they changed.
+UPDATE audit_logs
+SET old_values = JSON_REPLACE(old_values, '$.address', '[redacted]', '$.contact_name', '[redacted]', '$.mobile_number', '[redacted]')
+WHERE table_name = 'posts' AND old_values IS NOT NULL;
+
+UPDATE audit_logs
+SET new_values = JSON_REPLACE(new_values, '$.address', '[redacted]', '$.contact_name', '[redacted]', '$.mobile_number', '[redacted]')
+WHERE table_name = 'posts' AND new_values IS NOT NULL;
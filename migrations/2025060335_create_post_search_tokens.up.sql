@@ -0,0 +1,10 @@
+-- Blind indexes of the words in posts.address and posts.contact_name, which
+-- are encrypted and cannot be in the full-text index. Existing posts are
+-- filled by `go run ./cmd/migrate reencrypt`.
+CREATE TABLE post_search_tokens (
+    post_id BIGINT NOT NULL,
+    token CHAR(64) NOT NULL,
+    PRIMARY KEY (post_id, token),
+    INDEX idx_post_search_tokens_token (token),
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
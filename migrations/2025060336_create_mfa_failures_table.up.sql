@@ -0,0 +1,11 @@
+-- Failed second-factor attempts, counted per MFA challenge and per user to
+-- stop guessing codes
+CREATE TABLE mfa_failures (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    user_id BIGINT NOT NULL,
+    challenge_id VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_mfa_failures_challenge_id (challenge_id),
+    INDEX idx_mfa_failures_user_created (user_id, created_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);